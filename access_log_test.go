@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAccessLogSizes(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	f, err := os.Create(filepath.Join(tmpDir, "access.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lg := &requestLogger{f: f}
+
+	reqBody := `{"org":"org","repo":"repo","title":"Goal","body":"Body"}`
+	req := httptest.NewRequest("POST", "/goals", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "192.0.2.7:51234"
+	req.Header.Set("User-Agent", "ralph-test/1.0")
+	w := httptest.NewRecorder()
+	lg.wrap(mux).ServeHTTP(w, req)
+	if w.Code != 201 {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	f.Seek(0, io.SeekStart)
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() {
+		t.Fatal("expected a log entry")
+	}
+	var entry logEntry
+	if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.BytesIn != int64(len(reqBody)) {
+		t.Fatalf("expected bytes_in=%d, got %d", len(reqBody), entry.BytesIn)
+	}
+	if entry.BytesOut != int64(w.Body.Len()) {
+		t.Fatalf("expected bytes_out=%d, got %d", w.Body.Len(), entry.BytesOut)
+	}
+	if entry.ClientIP != "192.0.2.7" {
+		t.Fatalf("expected client_ip=192.0.2.7, got %q", entry.ClientIP)
+	}
+	if entry.UserAgent != "ralph-test/1.0" {
+		t.Fatalf("expected user_agent=ralph-test/1.0, got %q", entry.UserAgent)
+	}
+}
+
+func TestAccessLogGets(t *testing.T) {
+	tmpDir := t.TempDir()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, 200, map[string]any{"ok": true})
+	})
+
+	countLines := func(lg *requestLogger, n int) int {
+		for i := 0; i < n; i++ {
+			req := httptest.NewRequest("GET", "/goals/1", nil)
+			lg.wrap(h).ServeHTTP(httptest.NewRecorder(), req)
+		}
+		lg.f.Seek(0, io.SeekStart)
+		sc := bufio.NewScanner(lg.f)
+		lines := 0
+		for sc.Scan() {
+			lines++
+		}
+		return lines
+	}
+
+	newLogger := func(name string, logGets bool, rate float64) *requestLogger {
+		f, err := os.Create(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		return &requestLogger{f: f, logGets: logGets, getSampleRate: rate}
+	}
+
+	t.Run("skipped by default", func(t *testing.T) {
+		if n := countLines(newLogger("off.jsonl", false, 1), 5); n != 0 {
+			t.Fatalf("expected no GET entries, got %d", n)
+		}
+	})
+
+	t.Run("logged when enabled", func(t *testing.T) {
+		if n := countLines(newLogger("on.jsonl", true, 1), 5); n != 5 {
+			t.Fatalf("expected 5 GET entries, got %d", n)
+		}
+	})
+
+	t.Run("zero sample rate logs nothing", func(t *testing.T) {
+		if n := countLines(newLogger("zero.jsonl", true, 0), 5); n != 0 {
+			t.Fatalf("expected no GET entries, got %d", n)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestActivity(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	id, err := createGoal(context.Background(), db, "org", "repo", "Goal", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pin timestamps so comments and transitions interleave
+	stmts := []struct {
+		query string
+		args  []any
+	}{
+		{`UPDATE goal_transitions SET created_at = '2026-01-01T00:00:00Z' WHERE goal_id = ?`, []any{id}},
+		{`INSERT INTO goal_comments (goal_id, body, created_at) VALUES (?, 'planning', '2026-01-01T00:01:00Z')`, []any{id}},
+		{`INSERT INTO goal_transitions (goal_id, from_status, to_status, created_at) VALUES (?, 'draft', 'queued', '2026-01-01T00:02:00Z')`, []any{id}},
+		{`INSERT INTO goal_comments (goal_id, body, created_at) VALUES (?, 'picked up', '2026-01-01T00:03:00Z')`, []any{id}},
+		{`INSERT INTO goal_transitions (goal_id, from_status, to_status, note, created_at) VALUES (?, 'queued', 'running', 'worker-1', '2026-01-01T00:04:00Z')`, []any{id}},
+	}
+	for _, st := range stmts {
+		if _, err := db.Exec(st.query, st.args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(query string) map[string]any {
+		req := httptest.NewRequest("GET", "/goals/"+strconv.FormatInt(id, 10)+"/activity"+query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+
+	t.Run("merged in chronological order", func(t *testing.T) {
+		items := get("")["items"].([]any)
+		want := []struct{ typ, detail string }{
+			{"transition", "draft"},
+			{"comment", "planning"},
+			{"transition", "queued"},
+			{"comment", "picked up"},
+			{"transition", "running"},
+		}
+		if len(items) != len(want) {
+			t.Fatalf("expected %d items, got %d", len(want), len(items))
+		}
+		for i, item := range items {
+			m := item.(map[string]any)
+			detail := m["to_status"]
+			if want[i].typ == "comment" {
+				detail = m["body"]
+			}
+			if m["type"] != want[i].typ || detail != want[i].detail {
+				t.Fatalf("item %d: expected %s %s, got %v", i, want[i].typ, want[i].detail, m)
+			}
+		}
+		if last := items[4].(map[string]any); last["note"] != "worker-1" {
+			t.Fatalf("expected transition note, got %v", last["note"])
+		}
+	})
+
+	t.Run("paginated", func(t *testing.T) {
+		resp := get("?page=2&per_page=2")
+		if int(resp["total"].(float64)) != 5 {
+			t.Fatalf("expected total 5, got %v", resp["total"])
+		}
+		items := resp["items"].([]any)
+		if len(items) != 2 {
+			t.Fatalf("expected 2 items, got %d", len(items))
+		}
+		if m := items[0].(map[string]any); m["type"] != "transition" || m["to_status"] != "queued" {
+			t.Fatalf("expected draft->queued first on page 2, got %v", m)
+		}
+	})
+
+	t.Run("missing goal returns 404", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/goals/9999/activity", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 404 {
+			t.Fatalf("expected 404, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// adminAPIKey guards the /admin routes. When empty the admin API is
+// disabled entirely.
+var adminAPIKey = ""
+
+// requireAdmin only lets requests through that carry the admin key as a
+// bearer token.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminAPIKey == "" {
+			writeErr(w, 403, "admin API is disabled")
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminAPIKey)) != 1 {
+			writeErr(w, 401, "admin key required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// pprofEnabled mounts the net/http/pprof handlers under /debug/pprof/,
+// behind the admin key. Off by default since profiles expose internals.
+var pprofEnabled = false
+
+// pprofRoutes are the /debug/pprof/ handlers; Index also serves the named
+// profiles such as goroutine and heap.
+var pprofRoutes = map[string]http.HandlerFunc{
+	"GET /debug/pprof/":        pprof.Index,
+	"GET /debug/pprof/cmdline": pprof.Cmdline,
+	"GET /debug/pprof/profile": pprof.Profile,
+	"GET /debug/pprof/symbol":  pprof.Symbol,
+	"GET /debug/pprof/trace":   pprof.Trace,
+}
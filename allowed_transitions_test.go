@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestAllowedTransitions(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	allowed := func(id int64) []any {
+		req := httptest.NewRequest("GET", "/goals/"+strconv.FormatInt(id, 10), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp["allowed_transitions"].([]any)
+	}
+
+	blocker, err := createGoal(context.Background(), db, "org", "repo", "Blocker", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocked, err := createGoal(context.Background(), db, "org", "repo", "Blocked", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addDependency(context.Background(), db, blocked, blocker); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateGoalStatus(context.Background(), db, blocked, "draft", "queued"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("queued goal with unmet dependency cannot start", func(t *testing.T) {
+		got := allowed(blocked)
+		if len(got) != 1 || got[0] != "cancelled" {
+			t.Fatalf("expected [cancelled], got %v", got)
+		}
+	})
+
+	t.Run("start advertised once dependency is done", func(t *testing.T) {
+		transitionToRunning(t, db, blocker)
+		if err := updateGoalStatus(context.Background(), db, blocker, "running", "done"); err != nil {
+			t.Fatal(err)
+		}
+		got := allowed(blocked)
+		if len(got) != 2 || got[0] != "running" || got[1] != "cancelled" {
+			t.Fatalf("expected [running cancelled], got %v", got)
+		}
+	})
+
+	t.Run("terminal goal has empty list", func(t *testing.T) {
+		if got := allowed(blocker); len(got) != 0 {
+			t.Fatalf("expected no transitions, got %v", got)
+		}
+	})
+}
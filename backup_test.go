@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdminBackup(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, title := range []string{"First", "Second"} {
+		if _, err := createGoal(context.Background(), db, "org", "repo", title, "Body", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	adminAPIKey = "secret"
+	defer func() { adminAPIKey = "" }()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	get := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/admin/backup", nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("requires the admin key", func(t *testing.T) {
+		if w := get(""); w.Code != 401 {
+			t.Fatalf("expected 401 without key, got %d", w.Code)
+		}
+		if w := get("wrong"); w.Code != 401 {
+			t.Fatalf("expected 401 with wrong key, got %d", w.Code)
+		}
+	})
+
+	t.Run("downloads a valid database", func(t *testing.T) {
+		w := get("secret")
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") {
+			t.Fatalf("expected attachment Content-Disposition, got %q", cd)
+		}
+
+		backupPath := filepath.Join(tmpDir, "backup.db")
+		if err := os.WriteFile(backupPath, w.Body.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		backup, err := sql.Open("sqlite", backupPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer backup.Close()
+
+		var n int
+		if err := backup.QueryRow(`SELECT COUNT(*) FROM goals`).Scan(&n); err != nil {
+			t.Fatalf("backup is not a readable database: %v", err)
+		}
+		if n != 2 {
+			t.Fatalf("expected 2 goals in backup, got %d", n)
+		}
+	})
+
+	t.Run("disabled without a configured key", func(t *testing.T) {
+		adminAPIKey = ""
+		if w := get("secret"); w.Code != 403 {
+			t.Fatalf("expected 403, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeSQLiteError struct{ code int }
+
+func (e *fakeSQLiteError) Error() string { return "database is locked" }
+func (e *fakeSQLiteError) Code() int     { return e.code }
+
+func TestInTxRetriesBusy(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	t.Run("retries until the transaction succeeds", func(t *testing.T) {
+		calls := 0
+		err := inTx(ctx, db, func(tx *sql.Tx) error {
+			calls++
+			if calls < 3 {
+				return &fakeSQLiteError{code: 5}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected success after retries, got %v", err)
+		}
+		if calls != 3 {
+			t.Fatalf("expected 3 attempts, got %d", calls)
+		}
+	})
+
+	t.Run("gives up after busyRetries", func(t *testing.T) {
+		calls := 0
+		err := inTx(ctx, db, func(tx *sql.Tx) error {
+			calls++
+			return &fakeSQLiteError{code: 6}
+		})
+		if err == nil {
+			t.Fatal("expected error once retries are exhausted")
+		}
+		if calls != busyRetries+1 {
+			t.Fatalf("expected %d attempts, got %d", busyRetries+1, calls)
+		}
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		calls := 0
+		inTx(ctx, db, func(tx *sql.Tx) error {
+			calls++
+			return sql.ErrNoRows
+		})
+		if calls != 1 {
+			t.Fatalf("expected 1 attempt, got %d", calls)
+		}
+	})
+
+	t.Run("write succeeds under real contention", func(t *testing.T) {
+		id, err := createGoal(ctx, db, "org", "repo", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Surface SQLITE_BUSY immediately instead of waiting it out, so the
+		// retry loop is what rides over the contention
+		if _, err := db.Exec("PRAGMA busy_timeout=0"); err != nil {
+			t.Fatal(err)
+		}
+		defer db.Exec("PRAGMA busy_timeout=5000")
+
+		other, err := sql.Open("sqlite", dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer other.Close()
+		conn, err := other.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+			t.Fatal(err)
+		}
+		released := make(chan struct{})
+		go func() {
+			time.Sleep(75 * time.Millisecond)
+			conn.ExecContext(ctx, "COMMIT")
+			close(released)
+		}()
+
+		if err := updateGoalStatus(ctx, db, id, "draft", "queued"); err != nil {
+			t.Fatalf("expected write to succeed after retry, got %v", err)
+		}
+		<-released
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestCancelledDependency(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	// newBlocked returns a queued goal whose only dependency has been cancelled
+	newBlocked := func() (goal, dep int64) {
+		dep, err := createGoal(context.Background(), db, "org", "repo", "Dependency", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		goal, err = createGoal(context.Background(), db, "org", "repo", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := addDependency(context.Background(), db, goal, dep); err != nil {
+			t.Fatal(err)
+		}
+		if err := updateGoalStatus(context.Background(), db, goal, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+		if err := updateGoalStatus(context.Background(), db, dep, "draft", "cancelled"); err != nil {
+			t.Fatal(err)
+		}
+		return goal, dep
+	}
+
+	start := func(id int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/goals/"+strconv.FormatInt(id, 10)+"/start", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("blocks forever by default", func(t *testing.T) {
+		goal, dep := newBlocked()
+		w := start(goal)
+		if w.Code != 409 {
+			t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		want := "dependency " + strconv.FormatInt(dep, 10) + " was cancelled and can never be satisfied"
+		if resp["error"] != want {
+			t.Fatalf("expected %q, got %v", want, resp["error"])
+		}
+	})
+
+	t.Run("treated as satisfied when enabled", func(t *testing.T) {
+		cancelledDepsSatisfied = true
+		defer func() { cancelledDepsSatisfied = false }()
+
+		goal, _ := newBlocked()
+		if w := start(goal); w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestCascadeCancel(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	newGoal := func(title string) int64 {
+		id, err := createGoal(context.Background(), db, "org", "repo", title, "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	cancel := func(id int64, query string) map[string]any {
+		req := httptest.NewRequest("PATCH", "/goals/"+strconv.FormatInt(id, 10)+"/cancel"+query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+
+	status := func(id int64) string {
+		g, err := getGoal(context.Background(), db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g.Status
+	}
+
+	// chain builds A <- B <- C, where C depends on B and B depends on A
+	chain := func() (a, b, c int64) {
+		a, b, c = newGoal("A"), newGoal("B"), newGoal("C")
+		if err := addDependency(context.Background(), db, b, a); err != nil {
+			t.Fatal(err)
+		}
+		if err := addDependency(context.Background(), db, c, b); err != nil {
+			t.Fatal(err)
+		}
+		return a, b, c
+	}
+
+	t.Run("cascade cancels the whole chain", func(t *testing.T) {
+		a, b, c := chain()
+		resp := cancel(a, "?cascade=true")
+		cascaded := resp["cascaded"].([]any)
+		if len(cascaded) != 2 || int64(cascaded[0].(float64)) != b || int64(cascaded[1].(float64)) != c {
+			t.Fatalf("expected cascaded [%d %d], got %v", b, c, cascaded)
+		}
+		for _, id := range []int64{a, b, c} {
+			if s := status(id); s != "cancelled" {
+				t.Fatalf("expected goal %d cancelled, got %s", id, s)
+			}
+		}
+		comments, err := listComments(context.Background(), db, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comments) != 1 {
+			t.Fatalf("expected a cascade comment on C, got %d comments", len(comments))
+		}
+	})
+
+	t.Run("without cascade dependents are untouched", func(t *testing.T) {
+		a, b, c := chain()
+		resp := cancel(a, "")
+		if _, ok := resp["cascaded"]; ok {
+			t.Fatalf("expected no cascaded field, got %v", resp["cascaded"])
+		}
+		if status(b) != "draft" || status(c) != "draft" {
+			t.Fatalf("expected dependents to stay draft, got %s and %s", status(b), status(c))
+		}
+	})
+}
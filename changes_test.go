@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestChangeFeed(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	type feed struct {
+		Items  []Change `json:"items"`
+		Cursor int64    `json:"cursor"`
+		More   bool     `json:"more"`
+	}
+	get := func(query string) feed {
+		req := httptest.NewRequest("GET", "/changes"+query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var f feed
+		json.NewDecoder(w.Body).Decode(&f)
+		return f
+	}
+
+	ctx := context.Background()
+	a, err := createGoal(ctx, db, "org", "repo", "A", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := createGoal(ctx, db, "org", "repo", "B", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two creations so far
+	first := get("")
+	if len(first.Items) != 2 || first.More {
+		t.Fatalf("expected 2 changes, got %d (more %v)", len(first.Items), first.More)
+	}
+
+	if err := updateGoalStatus(ctx, db, a, "draft", "queued"); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateGoalStatus(ctx, db, a, "queued", "running"); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateGoalStatus(ctx, db, b, "draft", "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+
+	next := get("?since=" + strconv.FormatInt(first.Cursor, 10))
+	if len(next.Items) != 3 {
+		t.Fatalf("expected only the 3 changes after the cursor, got %d", len(next.Items))
+	}
+	want := []struct {
+		goal    int64
+		to      string
+		goalNow string
+	}{{a, "queued", "running"}, {a, "running", "running"}, {b, "cancelled", "cancelled"}}
+	for i, c := range next.Items {
+		if c.ID <= first.Cursor {
+			t.Fatalf("change %d is not after cursor %d", c.ID, first.Cursor)
+		}
+		if c.GoalID != want[i].goal || c.ToStatus != want[i].to || c.GoalStatus != want[i].goalNow || c.Org != "org" {
+			t.Fatalf("change %d: expected goal %d -> %s (now %s), got %+v", i, want[i].goal, want[i].to, want[i].goalNow, c)
+		}
+	}
+	if next.Cursor != next.Items[2].ID {
+		t.Fatalf("expected cursor %d, got %d", next.Items[2].ID, next.Cursor)
+	}
+
+	// Nothing new keeps the cursor where it was
+	idle := get("?since=" + strconv.FormatInt(next.Cursor, 10))
+	if len(idle.Items) != 0 || idle.Cursor != next.Cursor {
+		t.Fatalf("expected no changes and cursor %d, got %d items cursor %d", next.Cursor, len(idle.Items), idle.Cursor)
+	}
+
+	// A limit pages through the feed
+	page := get("?limit=2")
+	if len(page.Items) != 2 || !page.More {
+		t.Fatalf("expected 2 changes with more, got %d (more %v)", len(page.Items), page.More)
+	}
+}
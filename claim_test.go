@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestClaimNext(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	claim := func(payload any) *httptest.ResponseRecorder {
+		var body []byte
+		if payload != nil {
+			body, _ = json.Marshal(payload)
+		}
+		req := httptest.NewRequest("POST", "/goals/next", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	newQueued := func(org string) int64 {
+		id, err := createGoal(context.Background(), db, org, "repo", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	t.Run("nothing ready returns 204", func(t *testing.T) {
+		if w := claim(nil); w.Code != 204 {
+			t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("claims oldest ready goal and skips blocked ones", func(t *testing.T) {
+		blocker, err := createGoal(context.Background(), db, "org", "repo", "Blocker", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocked := newQueued("org")
+		if err := addDependency(context.Background(), db, blocked, blocker); err != nil {
+			t.Fatal(err)
+		}
+		ready := newQueued("org")
+
+		w := claim(nil)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		if int64(resp["id"].(float64)) != ready {
+			t.Fatalf("expected goal %d, got %v", ready, resp["id"])
+		}
+		if resp["status"] != "running" {
+			t.Fatalf("expected status=running, got %v", resp["status"])
+		}
+
+		transitions, err := listTransitions(context.Background(), db, ready)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last := transitions[len(transitions)-1]
+		if *last.FromStatus != "queued" || last.ToStatus != "running" {
+			t.Fatalf("expected queued->running transition, got %+v", last)
+		}
+
+		if w := claim(nil); w.Code != 204 {
+			t.Fatalf("expected 204 with only a blocked goal left, got %d", w.Code)
+		}
+	})
+
+	t.Run("org filter scopes the claim", func(t *testing.T) {
+		newQueued("other-org")
+		if w := claim(map[string]any{"org": "nobody"}); w.Code != 204 {
+			t.Fatalf("expected 204 for unmatched org, got %d", w.Code)
+		}
+		if w := claim(map[string]any{"org": "other-org"}); w.Code != 200 {
+			t.Fatalf("expected 200 for matching org, got %d", w.Code)
+		}
+	})
+
+	t.Run("concurrent claims never share a goal", func(t *testing.T) {
+		const goals = 10
+		for i := 0; i < goals; i++ {
+			newQueued("race")
+		}
+
+		var mu sync.Mutex
+		claimed := map[int64]int{}
+		var wg sync.WaitGroup
+		for i := 0; i < goals*2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w := claim(map[string]any{"org": "race"})
+				if w.Code != 200 {
+					return
+				}
+				var resp map[string]any
+				json.NewDecoder(w.Body).Decode(&resp)
+				mu.Lock()
+				claimed[int64(resp["id"].(float64))]++
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		if len(claimed) != goals {
+			t.Fatalf("expected %d distinct goals claimed, got %d", goals, len(claimed))
+		}
+		for id, n := range claimed {
+			if n != 1 {
+				t.Fatalf("goal %d claimed %d times", id, n)
+			}
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestCloneGoal(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	clone := func(id int64, body string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest("POST", "/goals/"+strconv.FormatInt(id, 10)+"/clone", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w, resp
+	}
+
+	ctx := context.Background()
+	model, reasoning := "sonnet", "med"
+	srcID, err := createGoal(ctx, db, "org", "repo", "Fix flaky test", "Original body", &model, &reasoning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range [][2]string{{"draft", "queued"}, {"queued", "running"}, {"running", "done"}} {
+		if err := updateGoalStatus(ctx, db, srcID, step[0], step[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := createComment(ctx, db, srcID, "first attempt notes"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("fresh draft", func(t *testing.T) {
+		w, resp := clone(srcID, "")
+		if w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %v", w.Code, resp)
+		}
+		newID := int64(resp["id"].(float64))
+		if newID == srcID {
+			t.Fatal("expected a new goal id")
+		}
+		if w.Header().Get("Location") != "/goals/"+strconv.FormatInt(newID, 10) {
+			t.Fatalf("unexpected Location %q", w.Header().Get("Location"))
+		}
+		if resp["status"] != "draft" {
+			t.Fatalf("expected fresh draft, got status %v", resp["status"])
+		}
+		for field, want := range map[string]string{"org": "org", "repo": "repo", "title": "Fix flaky test", "body": "Original body", "model": "sonnet", "reasoning": "med"} {
+			if resp[field] != want {
+				t.Fatalf("expected %s %q, got %v", field, want, resp[field])
+			}
+		}
+		comments, err := listComments(ctx, db, newID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comments) != 0 {
+			t.Fatalf("expected no comments on clone, got %d", len(comments))
+		}
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		w, resp := clone(srcID, `{"title": "Fix flaky test (retry)", "body": "Tweaked body", "model": "opus", "reasoning": null}`)
+		if w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %v", w.Code, resp)
+		}
+		if resp["title"] != "Fix flaky test (retry)" || resp["body"] != "Tweaked body" {
+			t.Fatalf("expected overridden title/body, got %v/%v", resp["title"], resp["body"])
+		}
+		if resp["model"] != "opus" || resp["reasoning"] != nil {
+			t.Fatalf("expected opus with no reasoning, got %v/%v", resp["model"], resp["reasoning"])
+		}
+	})
+
+	t.Run("invalid override rejected", func(t *testing.T) {
+		if w, _ := clone(srcID, `{"model": "gpt"}`); w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("missing goal", func(t *testing.T) {
+		if w, _ := clone(9999, ""); w.Code != 404 {
+			t.Fatalf("expected 404, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestEditDeleteComments(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	idA, err := createGoal(context.Background(), db, "org", "repo", "Goal A", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idB, err := createGoal(context.Background(), db, "org", "repo", "Goal B", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commentURL := func(goalID, commentID int64) string {
+		return "/goals/" + strconv.FormatInt(goalID, 10) + "/comments/" + strconv.FormatInt(commentID, 10)
+	}
+
+	send := func(method, url string, payload any) *httptest.ResponseRecorder {
+		var body []byte
+		if payload != nil {
+			body, _ = json.Marshal(payload)
+		}
+		req := httptest.NewRequest(method, url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("edit comment", func(t *testing.T) {
+		cid, err := createComment(context.Background(), db, idA, "wrong")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := send("PATCH", commentURL(idA, cid), map[string]any{"body": "right"})
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		comments, err := listComments(context.Background(), db, idA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(comments) != 1 || comments[0].Body != "right" {
+			t.Fatalf("expected edited comment, got %+v", comments)
+		}
+	})
+
+	t.Run("empty body rejected", func(t *testing.T) {
+		cid, err := createComment(context.Background(), db, idA, "keep")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := send("PATCH", commentURL(idA, cid), map[string]any{"body": ""})
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("cross-goal comment id returns 404", func(t *testing.T) {
+		cid, err := createComment(context.Background(), db, idA, "belongs to A")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w := send("PATCH", commentURL(idB, cid), map[string]any{"body": "hijack"}); w.Code != 404 {
+			t.Fatalf("expected 404 on PATCH, got %d", w.Code)
+		}
+		if w := send("DELETE", commentURL(idB, cid), nil); w.Code != 404 {
+			t.Fatalf("expected 404 on DELETE, got %d", w.Code)
+		}
+
+		comments, err := listComments(context.Background(), db, idA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range comments {
+			if c.ID == cid && c.Body != "belongs to A" {
+				t.Fatalf("expected comment untouched, got %q", c.Body)
+			}
+		}
+	})
+
+	t.Run("delete comment", func(t *testing.T) {
+		cid, err := createComment(context.Background(), db, idB, "remove me")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w := send("DELETE", commentURL(idB, cid), nil); w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := send("DELETE", commentURL(idB, cid), nil); w.Code != 404 {
+			t.Fatalf("expected 404 on second delete, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContentType(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	post := func(contentType, body string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest("POST", "/goals", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w, resp
+	}
+	goal := `{"org":"org","repo":"repo","title":"T","body":"B"}`
+
+	t.Run("form-encoded body is rejected", func(t *testing.T) {
+		w, resp := post("application/x-www-form-urlencoded", "org=org&repo=repo&title=T&body=B")
+		if w.Code != 415 {
+			t.Fatalf("expected 415, got %d", w.Code)
+		}
+		if resp["error"] != "Content-Type must be application/json" {
+			t.Fatalf("unexpected error %v", resp["error"])
+		}
+	})
+
+	t.Run("JSON with charset is accepted", func(t *testing.T) {
+		if w, _ := post("application/json; charset=utf-8", goal); w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("missing Content-Type is rejected", func(t *testing.T) {
+		if w, _ := post("", goal); w.Code != 415 {
+			t.Fatalf("expected 415, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("empty body needs no Content-Type", func(t *testing.T) {
+		if w, _ := post("", ""); w.Code != 400 {
+			t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("empty body is a clear 400", func(t *testing.T) {
+		w, resp := post("application/json", "")
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+		if resp["error"] != "request body is required" {
+			t.Fatalf("unexpected error %v", resp["error"])
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCORSOrigins(t *testing.T) {
+	lg := &requestLogger{
+		corsOrigins: []string{"http://localhost:5173", "https://staging.example.test"},
+		corsMethods: "DELETE, GET",
+	}
+	h := lg.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, 200, map[string]any{"ok": true})
+	}))
+
+	do := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/goals", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("allowed origin echoed", func(t *testing.T) {
+		w := do("GET", "https://staging.example.test")
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://staging.example.test" {
+			t.Fatalf("expected staging origin, got %q", got)
+		}
+		if got := w.Header().Get("Vary"); got != "Origin" {
+			t.Fatalf("expected Vary: Origin, got %q", got)
+		}
+	})
+
+	t.Run("disallowed origin omitted", func(t *testing.T) {
+		w := do("GET", "http://evil.example.test")
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Fatalf("expected no Allow-Origin, got %q", got)
+		}
+		if w.Code != 200 {
+			t.Fatalf("expected the request itself to be served, got %d", w.Code)
+		}
+		if got := w.Header().Get("Vary"); got != "Origin" {
+			t.Fatalf("expected Vary: Origin, got %q", got)
+		}
+	})
+
+	t.Run("preflight", func(t *testing.T) {
+		w := do("OPTIONS", "http://localhost:5173")
+		if w.Code != 204 {
+			t.Fatalf("expected 204, got %d", w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:5173" {
+			t.Fatalf("expected localhost origin, got %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != "DELETE, GET" {
+			t.Fatalf("expected the configured methods, got %q", got)
+		}
+	})
+}
+
+func TestCORSMethodsFromRoutes(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := openDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	methods := registerRoutes(mux, newSQLiteStore(db))
+	lg := &requestLogger{
+		corsOrigins: []string{"http://localhost:5173"},
+		corsMethods: strings.Join(methods, ", "),
+	}
+
+	req := httptest.NewRequest("OPTIONS", "/goals/1/dependencies/2", nil)
+	req.Header.Set("Origin", "http://localhost:5173")
+	req.Header.Set("Access-Control-Request-Method", "DELETE")
+	w := httptest.NewRecorder()
+	lg.wrap(mux).ServeHTTP(w, req)
+
+	if w.Code != 204 {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "DELETE, GET, PATCH, POST, PUT" {
+		t.Fatalf("expected DELETE, GET, PATCH, POST, PUT, got %q", got)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCreateGoalResponse(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	body, _ := json.Marshal(map[string]any{
+		"org":   "org",
+		"repo":  "repo",
+		"title": "New Goal",
+		"body":  "Body",
+	})
+	req := httptest.NewRequest("POST", "/goals", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != 201 {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp map[string]any
+	json.NewDecoder(w.Body).Decode(&resp)
+	id := int64(resp["id"].(float64))
+
+	if loc := w.Header().Get("Location"); loc != "/goals/"+strconv.FormatInt(id, 10) {
+		t.Fatalf("expected Location /goals/%d, got %q", id, loc)
+	}
+	if resp["status"] != "draft" {
+		t.Fatalf("expected status=draft, got %v", resp["status"])
+	}
+	if resp["title"] != "New Goal" {
+		t.Fatalf("expected title in body, got %v", resp["title"])
+	}
+	if resp["created_at"] == nil || resp["created_at"] == "" {
+		t.Fatal("expected created_at in body")
+	}
+	if _, ok := resp["allowed_transitions"]; !ok {
+		t.Fatal("expected the same shape as GET /goals/{id}")
+	}
+}
+
+func TestCreateGoalOrgRepoValidation(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	create := func(org, repo string) (*httptest.ResponseRecorder, map[string]any) {
+		body, _ := json.Marshal(map[string]any{"org": org, "repo": repo, "title": "T", "body": "B"})
+		req := httptest.NewRequest("POST", "/goals", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w, resp
+	}
+
+	t.Run("valid names accepted", func(t *testing.T) {
+		w, resp := create("ai4mgreenly", "ralph-plans.v2_x")
+		if w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %v", w.Code, resp)
+		}
+	})
+
+	t.Run("surrounding whitespace trimmed", func(t *testing.T) {
+		w, resp := create("  org\t", "repo ")
+		if w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %v", w.Code, resp)
+		}
+		if resp["org"] != "org" || resp["repo"] != "repo" {
+			t.Fatalf("expected trimmed org/repo, got %q/%q", resp["org"], resp["repo"])
+		}
+	})
+
+	t.Run("invalid names rejected", func(t *testing.T) {
+		for _, c := range [][2]string{{"my org", "repo"}, {"org", "owner/repo"}, {"org", "répo"}, {"   ", "repo"}} {
+			if w, _ := create(c[0], c[1]); w.Code != 400 {
+				t.Fatalf("%q/%q: expected 400, got %d", c[0], c[1], w.Code)
+			}
+		}
+	})
+}
+
+func TestCreateGoalContentLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	create := func(title, body string) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(map[string]any{"org": "org", "repo": "repo", "title": title, "body": body})
+		req := httptest.NewRequest("POST", "/goals", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("title at limit accepted", func(t *testing.T) {
+		if w := create(strings.Repeat("é", maxTitleLen), "B"); w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("over-long title rejected", func(t *testing.T) {
+		w := create(strings.Repeat("a", maxTitleLen+1), "B")
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "title") {
+			t.Fatalf("expected error to name the title, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("over-long body rejected", func(t *testing.T) {
+		w := create("T", strings.Repeat("a", maxBodyLen+1))
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "body") {
+			t.Fatalf("expected error to name the body, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("whitespace-only title rejected", func(t *testing.T) {
+		if w := create(" \t\n", "B"); w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestCreateGoalFieldErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	create := func(payload map[string]any) (int, map[string]string) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", "/goals", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp struct {
+			Code   string            `json:"code"`
+			Fields map[string]string `json:"fields"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if w.Code == 400 && resp.Code != codeValidation {
+			t.Fatalf("expected code %s, got %s", codeValidation, w.Body.String())
+		}
+		return w.Code, resp.Fields
+	}
+
+	t.Run("only missing body flagged", func(t *testing.T) {
+		code, fields := create(map[string]any{"org": "org", "repo": "repo", "title": "T"})
+		if code != 400 {
+			t.Fatalf("expected 400, got %d", code)
+		}
+		if len(fields) != 1 || fields["body"] != "required" {
+			t.Fatalf("expected only body flagged, got %v", fields)
+		}
+	})
+
+	t.Run("every invalid field flagged", func(t *testing.T) {
+		code, fields := create(map[string]any{
+			"org": "bad org", "repo": "repo", "title": "T", "body": "B",
+			"model": "gpt", "reasoning": "max", "priority": 101,
+		})
+		if code != 400 {
+			t.Fatalf("expected 400, got %d", code)
+		}
+		for _, f := range []string{"org", "model", "reasoning", "priority"} {
+			if fields[f] == "" {
+				t.Fatalf("expected %s flagged, got %v", f, fields)
+			}
+		}
+		if len(fields) != 4 {
+			t.Fatalf("expected 4 fields flagged, got %v", fields)
+		}
+	})
+}
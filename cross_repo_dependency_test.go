@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestCrossRepoDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	newGoal := func(org, repo string) int64 {
+		id, err := createGoal(context.Background(), db, org, repo, "Title", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	addDep := func(id int64, payload map[string]any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", "/goals/"+strconv.FormatInt(id, 10)+"/dependencies", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("same repo accepted", func(t *testing.T) {
+		a, b := newGoal("orgA", "repoX"), newGoal("orgA", "repoX")
+		if w := addDep(a, map[string]any{"depends_on_id": b}); w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("cross repo rejected by default", func(t *testing.T) {
+		a := newGoal("orgA", "repoX")
+		other := newGoal("orgB", "repoY")
+		sameOrg := newGoal("orgA", "repoZ")
+		if w := addDep(a, map[string]any{"depends_on_id": other}); w.Code != 409 {
+			t.Fatalf("expected 409 across orgs, got %d", w.Code)
+		}
+		if w := addDep(a, map[string]any{"depends_on_id": sameOrg}); w.Code != 409 {
+			t.Fatalf("expected 409 across repos, got %d", w.Code)
+		}
+		if w := addDep(a, map[string]any{"depends_on_ids": []int64{other}}); w.Code != 409 {
+			t.Fatalf("expected 409 for batch, got %d", w.Code)
+		}
+		deps, err := listDependencies(context.Background(), db, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deps) != 0 {
+			t.Fatalf("expected no dependencies, got %v", deps)
+		}
+	})
+
+	t.Run("cross repo allowed by flag", func(t *testing.T) {
+		allowCrossRepoDeps = true
+		t.Cleanup(func() { allowCrossRepoDeps = false })
+
+		a := newGoal("orgA", "repoX")
+		b, c := newGoal("orgB", "repoY"), newGoal("orgC", "repoZ")
+		if w := addDep(a, map[string]any{"depends_on_id": b}); w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := addDep(a, map[string]any{"depends_on_ids": []int64{c}}); w.Code != 201 {
+			t.Fatalf("expected 201 for batch, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
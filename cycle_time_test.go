@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestDurationStats(t *testing.T) {
+	got := durationStats([]int64{50, 10, 40, 20, 30, 60, 70, 80, 90, 100})
+	want := DurationStats{Count: 10, Avg: 55, P50: 50, P90: 90}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if got := durationStats(nil); got != (DurationStats{}) {
+		t.Fatalf("expected zero stats, got %+v", got)
+	}
+	if got := durationStats([]int64{7}); got.P50 != 7 || got.P90 != 7 {
+		t.Fatalf("expected the single value as every percentile, got %+v", got)
+	}
+}
+
+func TestCycleTime(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	ctx := context.Background()
+	// finish creates a done goal queued at 10:00 and done minutes later on
+	// the given day
+	finish := func(repo, day string, minutes int) {
+		id, err := createGoal(ctx, db, "org", repo, "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, step := range [][2]string{{"draft", "queued"}, {"queued", "running"}, {"running", "done"}} {
+			if err := updateGoalStatus(ctx, db, id, step[0], step[1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		done := fmt.Sprintf("%sT10:%02d:00Z", day, minutes)
+		for status, at := range map[string]string{"queued": day + "T10:00:00Z", "running": day + "T10:00:00Z", "done": done} {
+			if _, err := db.Exec(`UPDATE goal_transitions SET created_at = ? WHERE goal_id = ? AND to_status = ?`, at, id, status); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	finish("a", "2026-03-01", 10)
+	finish("a", "2026-03-01", 20)
+	finish("b", "2026-03-02", 45)
+	// Still queued, so not counted
+	if id, err := createGoal(ctx, db, "org", "a", "Open", "Body", nil, nil); err != nil {
+		t.Fatal(err)
+	} else if err := updateGoalStatus(ctx, db, id, "draft", "queued"); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(query string) (int, map[string]json.RawMessage) {
+		req := httptest.NewRequest("GET", "/goals/stats/cycle-time?"+query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp map[string]json.RawMessage
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+	stats := func(raw json.RawMessage) DurationStats {
+		var s DurationStats
+		if err := json.Unmarshal(raw, &s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	code, resp := get("")
+	if code != 200 {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	// (600 + 1200 + 2700) / 3
+	if s := stats(resp["cycle_time"]); s.Count != 3 || s.Avg != 1500 || s.P50 != 1200 || s.P90 != 2700 {
+		t.Fatalf("unexpected overall stats %+v", s)
+	}
+
+	t.Run("date range", func(t *testing.T) {
+		_, resp := get("done_before=2026-03-02")
+		if s := stats(resp["cycle_time"]); s.Count != 2 || s.Avg != 900 {
+			t.Fatalf("expected the two goals done on 2026-03-01, got %+v", s)
+		}
+	})
+
+	t.Run("grouped by repo", func(t *testing.T) {
+		_, resp := get("group_by=repo")
+		var groups []struct {
+			Repo      string          `json:"repo"`
+			CycleTime json.RawMessage `json:"cycle_time"`
+		}
+		json.Unmarshal(resp["groups"], &groups)
+		if len(groups) != 2 || groups[0].Repo != "a" || groups[1].Repo != "b" {
+			t.Fatalf("expected groups for a and b, got %s", resp["groups"])
+		}
+		if s := stats(groups[0].CycleTime); s.Count != 2 || s.Avg != 900 {
+			t.Fatalf("unexpected stats for a: %+v", s)
+		}
+	})
+
+	t.Run("invalid group_by", func(t *testing.T) {
+		if code, _ := get("group_by=status"); code != 400 {
+			t.Fatalf("expected 400, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreatedAtRangeFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stamps := []string{
+		"2023-12-31T23:59:59Z",
+		"2024-01-01T00:00:00Z", // exactly on the lower bound
+		"2024-01-15T12:00:00Z",
+		"2024-02-01T00:00:00Z", // exactly on the upper bound
+	}
+	var ids []int64
+	for _, ts := range stamps {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`UPDATE goals SET created_at = ? WHERE id = ?`, ts, id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	listIDs := func(url string) []int64 {
+		w := get(url)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		var got []int64
+		for _, item := range resp["items"].([]any) {
+			got = append(got, int64(item.(map[string]any)["id"].(float64)))
+		}
+		return got
+	}
+
+	t.Run("inclusive lower, exclusive upper", func(t *testing.T) {
+		got := listIDs("/goals?created_after=2024-01-01&created_before=2024-02-01")
+		if len(got) != 2 || got[0] != ids[2] || got[1] != ids[1] {
+			t.Fatalf("expected [%d %d], got %v", ids[2], ids[1], got)
+		}
+	})
+
+	t.Run("RFC3339 with offset is normalized to UTC", func(t *testing.T) {
+		// 2024-01-15T07:00:00-05:00 is 12:00 UTC, so the mid-January goal is included
+		got := listIDs("/goals?created_after=2024-01-15T07:00:00-05:00")
+		if len(got) != 2 || got[0] != ids[3] || got[1] != ids[2] {
+			t.Fatalf("expected [%d %d], got %v", ids[3], ids[2], got)
+		}
+	})
+
+	t.Run("unparseable value rejected", func(t *testing.T) {
+		if w := get("/goals?created_after=last-week"); w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+}
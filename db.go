@@ -1,25 +1,49 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
 )
 
+// timestampLayout is the single format for every stored timestamp. Writing
+// them all from Go in this fixed UTC layout keeps string comparison and
+// sorting on created_at/updated_at chronological.
+const timestampLayout = "2006-01-02T15:04:05Z"
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
 type Goal struct {
-	ID        int64   `json:"id"`
-	Org       string  `json:"org"`
-	Repo      string  `json:"repo"`
-	Title     string  `json:"title"`
-	Body      string  `json:"body"`
-	Status    string  `json:"status"`
-	Retries   int     `json:"retries"`
-	Model     *string `json:"model"`
-	Reasoning *string `json:"reasoning"`
-	CreatedAt string  `json:"created_at"`
-	UpdatedAt string  `json:"updated_at"`
+	ID             int64           `json:"id"`
+	Org            string          `json:"org"`
+	Repo           string          `json:"repo"`
+	Title          string          `json:"title"`
+	Body           string          `json:"body"`
+	Status         string          `json:"status"`
+	Retries        int             `json:"retries"`
+	Model          *string         `json:"model"`
+	Reasoning      *string         `json:"reasoning"`
+	Priority       *int            `json:"priority"`
+	LeaseExpiresAt *string         `json:"lease_expires_at"`
+	StuckReason    *string         `json:"stuck_reason"`
+	ParentID       *int64          `json:"parent_id"`
+	Metadata       json.RawMessage `json:"metadata"`
+	CreatedAt      string          `json:"created_at"`
+	UpdatedAt      string          `json:"updated_at"`
+	Version        int64           `json:"version"`
 }
 
 type GoalSummary struct {
@@ -30,6 +54,7 @@ type GoalSummary struct {
 	Status    string  `json:"status"`
 	Model     *string `json:"model"`
 	Reasoning *string `json:"reasoning"`
+	Priority  *int    `json:"priority"`
 }
 
 type Comment struct {
@@ -39,6 +64,15 @@ type Comment struct {
 	CreatedAt string `json:"created_at"`
 }
 
+type Transition struct {
+	ID         int64   `json:"id"`
+	GoalID     int64   `json:"goal_id"`
+	FromStatus *string `json:"from_status"`
+	ToStatus   string  `json:"to_status"`
+	Note       *string `json:"note"`
+	CreatedAt  string  `json:"created_at"`
+}
+
 type Attachment struct {
 	ID        int64  `json:"id"`
 	GoalID    int64  `json:"goal_id"`
@@ -48,6 +82,45 @@ type Attachment struct {
 	UpdatedAt string `json:"updated_at"`
 }
 
+// ActivityItem is one entry of a goal's timeline: a comment (Body set) or a
+// status transition (FromStatus, ToStatus and Note set).
+type ActivityItem struct {
+	Type       string  `json:"type"`
+	ID         int64   `json:"id"`
+	Body       *string `json:"body,omitempty"`
+	FromStatus *string `json:"from_status,omitempty"`
+	ToStatus   *string `json:"to_status,omitempty"`
+	Note       *string `json:"note,omitempty"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// Change is a transition in the change feed, with the goal's org, repo and
+// status as of the read.
+type Change struct {
+	Transition
+	Org        string `json:"org"`
+	Repo       string `json:"repo"`
+	GoalStatus string `json:"goal_status"`
+}
+
+// OutboxEntry is a webhook delivery waiting to be (re)attempted.
+type OutboxEntry struct {
+	ID            int64  `json:"id"`
+	URL           string `json:"url"`
+	Payload       string `json:"payload"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt string `json:"next_attempt_at"`
+}
+
+// OrgDefaults are the model and reasoning given to an org's new goals when
+// the request leaves them out.
+type OrgDefaults struct {
+	Org       string  `json:"org"`
+	Model     *string `json:"model"`
+	Reasoning *string `json:"reasoning"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
 type AttachmentSummary struct {
 	ID        int64  `json:"id"`
 	GoalID    int64  `json:"goal_id"`
@@ -56,33 +129,87 @@ type AttachmentSummary struct {
 	UpdatedAt string `json:"updated_at"`
 }
 
+// Connection pool settings, applied once migrations are done. A single open
+// connection (the default) serializes every query behind any write; with
+// WAL, more connections let reads proceed alongside the one writer.
+var (
+	dbMaxOpenConns    = 1
+	dbMaxIdleConns    = 2 // database/sql's default
+	dbConnMaxLifetime time.Duration
+)
+
 func openDB(path string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite", path)
+	// Pragmas go in the DSN so every connection the pool opens gets them,
+	// not just the first
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, err
 	}
+	// Migrations toggle per-connection pragmas such as foreign_keys, so they
+	// must all run on one connection
 	db.SetMaxOpenConns(1)
 
-	pragmas := []string{
-		"PRAGMA journal_mode=WAL",
-		"PRAGMA foreign_keys=ON",
-		"PRAGMA busy_timeout=5000",
-	}
-	for _, p := range pragmas {
-		if _, err := db.Exec(p); err != nil {
-			db.Close()
-			return nil, err
-		}
-	}
-
 	if err := migrate(db); err != nil {
 		db.Close()
 		return nil, err
 	}
+
+	db.SetMaxOpenConns(dbMaxOpenConns)
+	db.SetMaxIdleConns(dbMaxIdleConns)
+	db.SetConnMaxLifetime(dbConnMaxLifetime)
 	return db, nil
 }
 
+// migrations are applied in order and recorded in schema_migrations, so
+// each runs exactly once per database; version N is migrations[N-1]. Append
+// new steps rather than editing applied ones. The early steps are idempotent
+// because databases created before versioning existed run them all.
+var migrations = []func(db *sql.DB) error{
+	migrateCreateTables,
+	migrateAddColumns,
+	migrateRebuildGoals,
+	migrateFixGoalsOldRefs,
+	migrateIndexDependsOn,
+	migrateCreateOutbox,
+	migrateAddStuckReason,
+	migrateAddTransitionNote,
+	migrateAddMetadata,
+	migrateCreateOrgDefaults,
+	migrateAddParentID,
+	migrateCreateOrgQuotas,
+	migrateAddVersion,
+}
+
 func migrate(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INTEGER PRIMARY KEY,
+		applied_at  TEXT    NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+	for v := current + 1; v <= len(migrations); v++ {
+		if err := migrations[v-1](db); err != nil {
+			return fmt.Errorf("migration %d: %w", v, err)
+		}
+		_, err := db.Exec(
+			`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+			v, timestamp(time.Now()),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateCreateTables creates the base schema.
+func migrateCreateTables(db *sql.DB) error {
 	stmts := []string{
 		`CREATE TABLE IF NOT EXISTS goals (
 			id          INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -91,10 +218,12 @@ func migrate(db *sql.DB) error {
 			title       TEXT    NOT NULL,
 			body        TEXT    NOT NULL,
 			status      TEXT    NOT NULL DEFAULT 'draft'
-			            CHECK (status IN ('draft','queued','running','done','stuck','cancelled')),
+			            CHECK (status IN (` + statusCheckList() + `)),
 			retries     INTEGER NOT NULL DEFAULT 0,
 			model       TEXT    CHECK (model IS NULL OR model IN ('haiku','sonnet','opus')),
 			reasoning   TEXT    CHECK (reasoning IS NULL OR reasoning IN ('none','low','med','high')),
+			priority    INTEGER CHECK (priority IS NULL OR priority BETWEEN 0 AND 100),
+			lease_expires_at TEXT,
 			created_at  TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
 			updated_at  TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
 		)`,
@@ -125,22 +254,34 @@ func migrate(db *sql.DB) error {
 			updated_at  TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
 			UNIQUE (goal_id, name)
 		)`,
+		`CREATE TABLE IF NOT EXISTS goal_tags (
+			goal_id     INTEGER NOT NULL REFERENCES goals(id),
+			tag         TEXT    NOT NULL,
+			PRIMARY KEY (goal_id, tag)
+		)`,
 		`CREATE INDEX IF NOT EXISTS idx_goals_status        ON goals(status)`,
 		`CREATE INDEX IF NOT EXISTS idx_goals_org_repo      ON goals(org, repo)`,
 		`CREATE INDEX IF NOT EXISTS idx_comments_goal_id    ON goal_comments(goal_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_transitions_goal_id ON goal_transitions(goal_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_attachments_goal_id ON goal_attachments(goal_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_tags_tag            ON goal_tags(tag)`,
 	}
 	for _, s := range stmts {
 		if _, err := db.Exec(s); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	// Add model and reasoning columns to existing tables (for backwards compatibility)
+// migrateAddColumns adds columns introduced after the initial schema to
+// tables created before them.
+func migrateAddColumns(db *sql.DB) error {
 	alterStmts := []string{
 		`ALTER TABLE goals ADD COLUMN model TEXT CHECK (model IS NULL OR model IN ('haiku','sonnet','opus'))`,
 		`ALTER TABLE goals ADD COLUMN reasoning TEXT CHECK (reasoning IS NULL OR reasoning IN ('none','low','med','high'))`,
+		`ALTER TABLE goals ADD COLUMN priority INTEGER CHECK (priority IS NULL OR priority BETWEEN 0 AND 100)`,
+		`ALTER TABLE goals ADD COLUMN lease_expires_at TEXT`,
 	}
 	for _, s := range alterStmts {
 		_, err := db.Exec(s)
@@ -152,8 +293,13 @@ func migrate(db *sql.DB) error {
 			return err
 		}
 	}
+	return nil
+}
 
-	// Recreate table if constraint is outdated (e.g. still has 'submitted'/'merged'/'rejected')
+// migrateRebuildGoals recreates the goals table if its status CHECK
+// constraint is outdated (e.g. still has 'submitted'/'merged'/'rejected'),
+// mapping retired statuses onto current ones.
+func migrateRebuildGoals(db *sql.DB) error {
 	tx, err := db.Begin()
 	if err != nil {
 		return err
@@ -176,21 +322,23 @@ func migrate(db *sql.DB) error {
 				title       TEXT    NOT NULL,
 				body        TEXT    NOT NULL,
 				status      TEXT    NOT NULL DEFAULT 'draft'
-				            CHECK (status IN ('draft','queued','running','done','stuck','cancelled')),
+				            CHECK (status IN (` + statusCheckList() + `)),
 				retries     INTEGER NOT NULL DEFAULT 0,
 				model       TEXT    CHECK (model IS NULL OR model IN ('haiku','sonnet','opus')),
 				reasoning   TEXT    CHECK (reasoning IS NULL OR reasoning IN ('none','low','med','high')),
+				priority    INTEGER CHECK (priority IS NULL OR priority BETWEEN 0 AND 100),
+				lease_expires_at TEXT,
 				created_at  TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
 				updated_at  TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
 			)`,
-			`INSERT INTO goals (id, org, repo, title, body, status, retries, model, reasoning, created_at, updated_at)
+			`INSERT INTO goals (id, org, repo, title, body, status, retries, model, reasoning, priority, lease_expires_at, created_at, updated_at)
 			 SELECT id, org, repo, title, body,
 			        CASE
 			            WHEN status IN ('submitted','merged') THEN 'done'
 			            WHEN status = 'rejected' THEN 'cancelled'
 			            ELSE status
 			        END,
-			        retries, model, reasoning, created_at, updated_at FROM goals_old`,
+			        retries, model, reasoning, priority, lease_expires_at, created_at, updated_at FROM goals_old`,
 			`DROP TABLE goals_old`,
 			`CREATE INDEX IF NOT EXISTS idx_goals_status ON goals(status)`,
 			`CREATE INDEX IF NOT EXISTS idx_goals_org_repo ON goals(org, repo)`,
@@ -202,8 +350,12 @@ func migrate(db *sql.DB) error {
 			}
 		}
 	}
+	return nil
+}
 
-	// Fix FK references in goal_transitions/goal_comments if they point to goals_old.
+// migrateFixGoalsOldRefs repoints goal_transitions/goal_comments foreign
+// keys that a goals rebuild left referencing goals_old.
+func migrateFixGoalsOldRefs(db *sql.DB) error {
 	var transitionsSQL string
 	db.QueryRow(`SELECT sql FROM sqlite_master WHERE name='goal_transitions'`).Scan(&transitionsSQL)
 	if strings.Contains(transitionsSQL, "goals_old") {
@@ -242,261 +394,2140 @@ func migrate(db *sql.DB) error {
 	return nil
 }
 
-func createGoal(db *sql.DB, org, repo, title, body string, model, reasoning *string) (int64, error) {
-	res, err := db.Exec(
-		`INSERT INTO goals (org, repo, title, body, model, reasoning) VALUES (?, ?, ?, ?, ?, ?)`,
-		org, repo, title, body, model, reasoning,
-	)
-	if err != nil {
-		return 0, err
-	}
-	return res.LastInsertId()
+// migrateIndexDependsOn indexes the reverse side of goal_dependencies; the
+// composite primary key only serves lookups by goal_id.
+func migrateIndexDependsOn(db *sql.DB) error {
+	_, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_deps_depends_on ON goal_dependencies(depends_on_id)`)
+	return err
 }
 
-func getGoal(db *sql.DB, id int64) (*Goal, error) {
-	row := db.QueryRow(
-		`SELECT id, org, repo, title, body, status, retries, model, reasoning, created_at, updated_at FROM goals WHERE id = ?`, id,
-	)
-	var g Goal
-	err := row.Scan(&g.ID, &g.Org, &g.Repo, &g.Title, &g.Body, &g.Status, &g.Retries, &g.Model, &g.Reasoning, &g.CreatedAt, &g.UpdatedAt)
-	if err != nil {
-		return nil, err
+// migrateAddStuckReason adds the explanation recorded when a goal is marked
+// stuck.
+func migrateAddStuckReason(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE goals ADD COLUMN stuck_reason TEXT`)
+	if err != nil && strings.Contains(err.Error(), "duplicate column name") {
+		return nil
 	}
-	return &g, nil
+	return err
 }
 
-func listGoals(db *sql.DB, status, org, repo string, ready bool, limit, offset int) ([]GoalSummary, int, error) {
-	// Build WHERE clause
-	whereClause := `WHERE 1=1`
-	var args []any
-	if status != "" {
-		whereClause += ` AND status = ?`
-		args = append(args, status)
-	}
-	if org != "" {
-		whereClause += ` AND org = ?`
-		args = append(args, org)
-	}
-	if repo != "" {
-		whereClause += ` AND repo = ?`
-		args = append(args, repo)
+// migrateAddTransitionNote adds the free-text context a caller may attach
+// to a status change.
+func migrateAddTransitionNote(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE goal_transitions ADD COLUMN note TEXT`)
+	if err != nil && strings.Contains(err.Error(), "duplicate column name") {
+		return nil
 	}
-	if ready {
-		whereClause += ` AND NOT EXISTS (
-			SELECT 1 FROM goal_dependencies gd
-			JOIN goals g2 ON g2.id = gd.depends_on_id
-			WHERE gd.goal_id = goals.id AND g2.status != 'done'
-		)`
+	return err
+}
+
+// migrateAddMetadata adds free-form JSON metadata to goals.
+func migrateAddMetadata(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE goals ADD COLUMN metadata TEXT`)
+	if err != nil && strings.Contains(err.Error(), "duplicate column name") {
+		return nil
 	}
+	return err
+}
 
-	// Get total count when pagination is requested
-	total := 0
-	if limit > 0 {
-		countQuery := `SELECT COUNT(*) FROM goals ` + whereClause
-		if err := db.QueryRow(countQuery, args...).Scan(&total); err != nil {
-			return nil, 0, err
+// migrateCreateOrgDefaults adds the per-org model and reasoning applied to
+// goals created without them.
+func migrateCreateOrgDefaults(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS org_defaults (
+		org         TEXT PRIMARY KEY,
+		model       TEXT CHECK (model IS NULL OR model IN ('haiku','sonnet','opus')),
+		reasoning   TEXT CHECK (reasoning IS NULL OR reasoning IN ('none','low','med','high')),
+		updated_at  TEXT NOT NULL
+	)`)
+	return err
+}
+
+// migrateAddParentID adds the optional parent goal used to group subtasks
+// under an epic.
+func migrateAddParentID(db *sql.DB) error {
+	stmts := []string{
+		`ALTER TABLE goals ADD COLUMN parent_id INTEGER REFERENCES goals(id)`,
+		`CREATE INDEX IF NOT EXISTS idx_goals_parent ON goals(parent_id)`,
+	}
+	for _, stmt := range stmts {
+		_, err := db.Exec(stmt)
+		if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return err
 		}
 	}
+	return nil
+}
 
-	// Build main query
-	query := `SELECT id, org, repo, title, status, model, reasoning FROM goals ` + whereClause + ` ORDER BY id DESC`
-	if limit > 0 {
-		query += ` LIMIT ? OFFSET ?`
-		args = append(args, limit, offset)
-	}
+// migrateCreateOrgQuotas adds per-org overrides of maxActivePerOrg.
+func migrateCreateOrgQuotas(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS org_quotas (
+		org         TEXT PRIMARY KEY,
+		max_active  INTEGER NOT NULL CHECK (max_active >= 0),
+		updated_at  TEXT NOT NULL
+	)`)
+	return err
+}
 
-	rows, err := db.Query(query, args...)
-	if err != nil {
-		return nil, 0, err
+// migrateAddVersion adds the counter behind goal ETags. It is bumped by
+// every write that changes a goal, so unlike updated_at it distinguishes
+// two writes within the same second.
+func migrateAddVersion(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE goals ADD COLUMN version INTEGER NOT NULL DEFAULT 1`)
+	if err != nil && strings.Contains(err.Error(), "duplicate column name") {
+		return nil
 	}
-	defer rows.Close()
+	return err
+}
 
-	var goals []GoalSummary
-	for rows.Next() {
-		var g GoalSummary
-		if err := rows.Scan(&g.ID, &g.Org, &g.Repo, &g.Title, &g.Status, &g.Model, &g.Reasoning); err != nil {
-			return nil, 0, err
+// migrateCreateOutbox adds the queue of pending webhook deliveries.
+func migrateCreateOutbox(db *sql.DB) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS outbox (
+			id               INTEGER PRIMARY KEY AUTOINCREMENT,
+			url              TEXT    NOT NULL,
+			payload          TEXT    NOT NULL,
+			attempts         INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at  TEXT    NOT NULL,
+			delivered_at     TEXT,
+			created_at       TEXT    NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(next_attempt_at) WHERE delivered_at IS NULL`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
 		}
-		goals = append(goals, g)
 	}
-	return goals, total, rows.Err()
+	return nil
 }
 
-func updateGoalStatus(db *sql.DB, id int64, from, to string) error {
-	now := time.Now().UTC().Format(time.RFC3339)
-	tx, err := db.Begin()
+// backupDB writes a consistent copy of the database to w. VACUUM INTO takes
+// a transactionally consistent snapshot even while the WAL is active; the
+// snapshot goes to a temp file that is removed afterwards.
+func backupDB(ctx context.Context, db *sql.DB, w io.Writer) error {
+	dir, err := os.MkdirTemp("", "ralph-plans-backup-")
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
+	defer os.RemoveAll(dir)
 
-	res, err := tx.Exec(
-		`UPDATE goals SET status = ?, updated_at = ? WHERE id = ? AND status = ?`,
-		to, now, id, from,
-	)
-	if err != nil {
-		return err
-	}
-	n, err := res.RowsAffected()
-	if err != nil {
+	path := filepath.Join(dir, "plans.db")
+	if _, err := db.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
 		return err
 	}
-	if n == 0 {
-		return sql.ErrNoRows
-	}
-
-	_, err = tx.Exec(
-		`INSERT INTO goal_transitions (goal_id, from_status, to_status) VALUES (?, ?, ?)`,
-		id, from, to,
-	)
+	f, err := os.Open(path)
 	if err != nil {
 		return err
 	}
-	return tx.Commit()
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
 }
 
-func createComment(db *sql.DB, goalID int64, body string) (int64, error) {
-	res, err := db.Exec(
-		`INSERT INTO goal_comments (goal_id, body) VALUES (?, ?)`,
-		goalID, body,
-	)
-	if err != nil {
-		return 0, err
-	}
-	return res.LastInsertId()
+// NewGoal holds the fields that can be set when a goal is created.
+type NewGoal struct {
+	Org       string
+	Repo      string
+	Title     string
+	Body      string
+	Model     *string
+	Reasoning *string
+	Priority  *int
+	ParentID  *int64
+	Metadata  json.RawMessage
 }
 
-func listComments(db *sql.DB, goalID int64) ([]Comment, error) {
-	rows, err := db.Query(
-		`SELECT id, goal_id, body, created_at FROM goal_comments WHERE goal_id = ? ORDER BY id`, goalID,
-	)
-	if err != nil {
-		return nil, err
+// busyRetries and busyBackoff control how write transactions retry when
+// SQLite reports the database as busy or locked. The wait doubles after
+// each attempt.
+var (
+	busyRetries = 3
+	busyBackoff = 50 * time.Millisecond
+)
+
+// isBusy reports whether err is SQLite's SQLITE_BUSY or SQLITE_LOCKED,
+// including their extended result codes.
+func isBusy(err error) bool {
+	var se interface{ Code() int }
+	if !errors.As(err, &se) {
+		return false
 	}
-	defer rows.Close()
+	code := se.Code() & 0xff
+	return code == 5 || code == 6
+}
 
-	var comments []Comment
-	for rows.Next() {
-		var c Comment
-		if err := rows.Scan(&c.ID, &c.GoalID, &c.Body, &c.CreatedAt); err != nil {
-			return nil, err
+// inTx runs fn in a transaction and commits it, retrying the whole
+// transaction with backoff if SQLite reports the database busy. fn may run
+// more than once, so it must reset any results it accumulates.
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	backoff := busyBackoff
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, db, fn)
+		if err == nil || !isBusy(err) || attempt >= busyRetries {
+			return err
 		}
-		comments = append(comments, c)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
 	}
-	return comments, rows.Err()
 }
 
-func addDependency(db *sql.DB, goalID, dependsOnID int64) error {
-	_, err := db.Exec(
-		`INSERT INTO goal_dependencies (goal_id, depends_on_id) VALUES (?, ?)`,
-		goalID, dependsOnID,
-	)
+// inTxNotify is inTx for transactions that record goal transitions. It
+// wakes transitionsChanged only once the transaction has committed, so a
+// woken waiter re-reading the feed is sure to find the change.
+func inTxNotify(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	err := inTx(ctx, db, fn)
+	if err == nil {
+		transitionsChanged.notify()
+	}
 	return err
 }
 
-func removeDependency(db *sql.DB, goalID, dependsOnID int64) error {
-	res, err := db.Exec(
-		`DELETE FROM goal_dependencies WHERE goal_id = ? AND depends_on_id = ?`,
-		goalID, dependsOnID,
-	)
+func runTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	n, err := res.RowsAffected()
-	if err != nil {
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
 		return err
 	}
-	if n == 0 {
-		return sql.ErrNoRows
-	}
-	return nil
+	return tx.Commit()
 }
 
-func listDependencies(db *sql.DB, goalID int64) ([]int64, error) {
-	rows, err := db.Query(
-		`SELECT depends_on_id FROM goal_dependencies WHERE goal_id = ? ORDER BY depends_on_id`,
-		goalID,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+func createGoal(ctx context.Context, db *sql.DB, org, repo, title, body string, model, reasoning *string) (int64, error) {
+	return insertGoal(ctx, db, NewGoal{Org: org, Repo: repo, Title: title, Body: body, Model: model, Reasoning: reasoning})
+}
 
-	var ids []int64
-	for rows.Next() {
-		var id int64
-		if err := rows.Scan(&id); err != nil {
-			return nil, err
+func insertGoal(ctx context.Context, db *sql.DB, ng NewGoal) (int64, error) {
+	var id int64
+	err := inTxNotify(ctx, db, func(tx *sql.Tx) error {
+		// Counted in the same transaction as the insert, so concurrent
+		// creates can't both slip under the cap
+		limit, err := orgQuota(ctx, tx, ng.Org)
+		if err != nil {
+			return err
 		}
-		ids = append(ids, id)
-	}
-	return ids, rows.Err()
+		if limit > 0 {
+			active, err := countActiveGoals(ctx, tx, ng.Org)
+			if err != nil {
+				return err
+			}
+			if active >= limit {
+				return &quotaError{Org: ng.Org, Limit: limit}
+			}
+		}
+
+		now := timestamp(time.Now())
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO goals (org, repo, title, body, model, reasoning, priority, parent_id, metadata, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			ng.Org, ng.Repo, ng.Title, ng.Body, ng.Model, ng.Reasoning, ng.Priority, ng.ParentID, nullableJSON(ng.Metadata), now, now,
+		)
+		if err != nil {
+			return err
+		}
+		id, err = res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		// Record the initial status so the transition history starts at creation
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO goal_transitions (goal_id, from_status, to_status, created_at) VALUES (?, NULL, 'draft', ?)`,
+			id, now,
+		)
+		return err
+	})
+	return id, err
 }
 
-func createAttachment(db *sql.DB, goalID int64, name, body string) (int64, error) {
-	res, err := db.Exec(
-		`INSERT INTO goal_attachments (goal_id, name, body) VALUES (?, ?, ?)`,
-		goalID, name, body,
-	)
-	if err != nil {
-		return 0, err
-	}
-	return res.LastInsertId()
+func getGoal(ctx context.Context, db *sql.DB, id int64) (*Goal, error) {
+	return scanGoal(db.QueryRowContext(ctx, `SELECT `+goalColumns+` FROM goals WHERE id = ?`, id))
 }
 
-func getAttachment(db *sql.DB, id int64) (*Attachment, error) {
-	row := db.QueryRow(
-		`SELECT id, goal_id, name, body, created_at, updated_at FROM goal_attachments WHERE id = ?`, id,
-	)
-	var a Attachment
-	err := row.Scan(&a.ID, &a.GoalID, &a.Name, &a.Body, &a.CreatedAt, &a.UpdatedAt)
+const goalColumns = `id, org, repo, title, body, status, retries, model, reasoning, priority, lease_expires_at, stuck_reason, parent_id, metadata, created_at, updated_at, version`
+
+// scanGoal reads a goal selected with goalColumns from a row or rows.
+func scanGoal(row interface{ Scan(...any) error }) (*Goal, error) {
+	var g Goal
+	var metadata []byte // json.RawMessage can't be scanned from NULL directly
+	err := row.Scan(&g.ID, &g.Org, &g.Repo, &g.Title, &g.Body, &g.Status, &g.Retries, &g.Model, &g.Reasoning, &g.Priority, &g.LeaseExpiresAt, &g.StuckReason, &g.ParentID, &metadata, &g.CreatedAt, &g.UpdatedAt, &g.Version)
 	if err != nil {
 		return nil, err
 	}
-	return &a, nil
+	g.Metadata = metadata
+	return &g, nil
 }
 
-func listAttachments(db *sql.DB, goalID int64) ([]AttachmentSummary, error) {
-	rows, err := db.Query(
-		`SELECT id, goal_id, name, created_at, updated_at FROM goal_attachments WHERE goal_id = ? ORDER BY id`, goalID,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// cancelledDepsSatisfied treats a cancelled dependency as met. When false
+// (the default) a cancelled dependency blocks its dependents permanently.
+var cancelledDepsSatisfied = false
 
-	var attachments []AttachmentSummary
-	for rows.Next() {
-		var a AttachmentSummary
-		if err := rows.Scan(&a.ID, &a.GoalID, &a.Name, &a.CreatedAt, &a.UpdatedAt); err != nil {
-			return nil, err
-		}
-		attachments = append(attachments, a)
+// allowCrossRepoDeps permits a goal to depend on one in another org/repo.
+// Such goals run independently, so by default the dependency is refused.
+var allowCrossRepoDeps = false
+
+// unmetDependencyCondition reports whether the dependency goal aliased as
+// alias still blocks its dependents.
+func unmetDependencyCondition(alias string) string {
+	if cancelledDepsSatisfied {
+		return alias + ".status NOT IN ('done','cancelled')"
 	}
-	return attachments, rows.Err()
+	return alias + ".status != 'done'"
 }
 
-func editAttachmentBody(db *sql.DB, id int64, newBody string) error {
-	now := time.Now().UTC().Format(time.RFC3339)
-	res, err := db.Exec(
-		`UPDATE goal_attachments SET body = ?, updated_at = ? WHERE id = ?`,
-		newBody, now, id,
-	)
-	if err != nil {
-		return err
+// unmetDependencySubquery selects the unfinished dependencies of the goal in
+// the outer query, so readiness is decided in SQL rather than per goal in Go.
+func unmetDependencySubquery() string {
+	return `SELECT 1 FROM goal_dependencies gd
+	JOIN goals g2 ON g2.id = gd.depends_on_id
+	WHERE gd.goal_id = goals.id AND ` + unmetDependencyCondition("g2")
+}
+
+// GoalFilter holds the optional conditions shared by the goal listing queries.
+type GoalFilter struct {
+	Statuses  []string // any of these; empty means every status
+	Excluded  []string // none of these statuses
+	Org       string
+	Repo      string
+	Ready     bool
+	Blocked   bool   // at least one unmet dependency; the inverse of Ready
+	Query     string // substring match on title or body
+	Tag       string
+	Model     string // "unset" matches goals without a model
+	Reasoning string // "unset" matches goals without a reasoning level
+	// CreatedAfter (inclusive) and CreatedBefore (exclusive) are timestamps
+	// in the stored created_at layout, so they compare as strings.
+	CreatedAfter  string
+	CreatedBefore string
+	Sort          string // key of goalSortColumns; empty means id
+	Order         string // "asc" or "desc"; empty means desc
+}
+
+// goalSortColumns maps the accepted sort keys to the columns they order by.
+// ORDER BY is only ever built from these constants, never from user input.
+var goalSortColumns = map[string]string{
+	"id":         "id",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// unsetFilter is the filter value that matches a NULL column.
+const unsetFilter = "unset"
+
+// placeholders returns n comma-separated "?" for an IN list.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func (f GoalFilter) where() (string, []any) {
+	whereClause := `WHERE 1=1`
+	var args []any
+	if len(f.Statuses) > 0 {
+		whereClause += ` AND status IN (` + placeholders(len(f.Statuses)) + `)`
+		for _, st := range f.Statuses {
+			args = append(args, st)
+		}
 	}
-	n, err := res.RowsAffected()
-	if err != nil {
-		return err
+	if len(f.Excluded) > 0 {
+		whereClause += ` AND status NOT IN (` + placeholders(len(f.Excluded)) + `)`
+		for _, st := range f.Excluded {
+			args = append(args, st)
+		}
 	}
-	if n == 0 {
-		return sql.ErrNoRows
+	if f.Org != "" {
+		whereClause += ` AND org = ?`
+		args = append(args, f.Org)
+	}
+	if f.Repo != "" {
+		whereClause += ` AND repo = ?`
+		args = append(args, f.Repo)
+	}
+	if f.Ready {
+		whereClause += ` AND NOT EXISTS (` + unmetDependencySubquery() + `)`
+	}
+	if f.Blocked {
+		whereClause += ` AND EXISTS (` + unmetDependencySubquery() + `)`
+	}
+	if f.Model == unsetFilter {
+		whereClause += ` AND model IS NULL`
+	} else if f.Model != "" {
+		whereClause += ` AND model = ?`
+		args = append(args, f.Model)
+	}
+	if f.Reasoning == unsetFilter {
+		whereClause += ` AND reasoning IS NULL`
+	} else if f.Reasoning != "" {
+		whereClause += ` AND reasoning = ?`
+		args = append(args, f.Reasoning)
+	}
+	if f.CreatedAfter != "" {
+		whereClause += ` AND created_at >= ?`
+		args = append(args, f.CreatedAfter)
+	}
+	if f.CreatedBefore != "" {
+		whereClause += ` AND created_at < ?`
+		args = append(args, f.CreatedBefore)
+	}
+	if f.Tag != "" {
+		whereClause += ` AND EXISTS (SELECT 1 FROM goal_tags gt WHERE gt.goal_id = goals.id AND gt.tag = ?)`
+		args = append(args, f.Tag)
+	}
+	if f.Query != "" {
+		// SQLite's LIKE is case-insensitive for ASCII
+		whereClause += ` AND (title LIKE ? ESCAPE '\' OR body LIKE ? ESCAPE '\')`
+		pattern := "%" + escapeLike(f.Query) + "%"
+		args = append(args, pattern, pattern)
+	}
+	return whereClause, args
+}
+
+// escapeLike escapes LIKE wildcards so s matches literally.
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+func (f GoalFilter) orderBy() string {
+	// The ready queue is served highest priority first, unprioritised goals
+	// last (NULLs sort last under DESC), oldest first within a priority.
+	if f.Ready && f.Sort == "" && f.Order == "" {
+		return ` ORDER BY priority DESC, id ASC`
+	}
+	col, ok := goalSortColumns[f.Sort]
+	if !ok {
+		col = "id"
+	}
+	dir := "DESC"
+	if f.Order == "asc" {
+		dir = "ASC"
+	}
+	if col == "id" {
+		return ` ORDER BY id ` + dir
+	}
+	// Break timestamp ties by id so pages stay stable
+	return ` ORDER BY ` + col + ` ` + dir + `, id ` + dir
+}
+
+func listGoals(ctx context.Context, db *sql.DB, f GoalFilter, limit, offset int) ([]GoalSummary, int, error) {
+	whereClause, args := f.where()
+
+	// Get total count when pagination is requested
+	total := 0
+	if limit > 0 {
+		countQuery := `SELECT COUNT(*) FROM goals ` + whereClause
+		if err := db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// Build main query
+	query := `SELECT ` + goalSummaryColumns + ` FROM goals ` + whereClause + f.orderBy()
+	if limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, offset)
+	}
+
+	goals, err := queryGoalSummaries(ctx, db, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return goals, total, nil
+}
+
+// listGoalsAfter returns up to limit goals with id below cursorID (or from the
+// newest goal when cursorID is 0), plus whether more goals follow.
+func listGoalsAfter(ctx context.Context, db *sql.DB, f GoalFilter, cursorID int64, limit int) ([]GoalSummary, bool, error) {
+	whereClause, args := f.where()
+	if cursorID > 0 {
+		whereClause += ` AND id < ?`
+		args = append(args, cursorID)
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	query := `SELECT ` + goalSummaryColumns + ` FROM goals ` + whereClause + ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit+1)
+
+	goals, err := queryGoalSummaries(ctx, db, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	more := len(goals) > limit
+	if more {
+		goals = goals[:limit]
+	}
+	return goals, more, nil
+}
+
+const goalSummaryColumns = `id, org, repo, title, status, model, reasoning, priority`
+
+func queryGoalSummaries(ctx context.Context, db *sql.DB, query string, args ...any) ([]GoalSummary, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []GoalSummary
+	for rows.Next() {
+		var g GoalSummary
+		if err := rows.Scan(&g.ID, &g.Org, &g.Repo, &g.Title, &g.Status, &g.Model, &g.Reasoning, &g.Priority); err != nil {
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	return goals, rows.Err()
+}
+
+// goalStats counts goals per status, including zero counts for unused statuses.
+func goalStats(ctx context.Context, db *sql.DB, org, repo string) (map[string]int, error) {
+	query := `SELECT status, COUNT(*) FROM goals WHERE 1=1`
+	var args []any
+	if org != "" {
+		query += ` AND org = ?`
+		args = append(args, org)
+	}
+	if repo != "" {
+		query += ` AND repo = ?`
+		args = append(args, repo)
+	}
+	query += ` GROUP BY status`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int, len(statuses))
+	for _, st := range statuses {
+		counts[st] = 0
+	}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+// CycleTime is how long one done goal took from first being queued to
+// reaching done.
+type CycleTime struct {
+	Org     string
+	Repo    string
+	Seconds int64
+}
+
+// cycleTimes returns the cycle time of every done goal in org and repo (if
+// set) that finished in [after, before), either bound optional. Goals done
+// without ever being queued are left out.
+func cycleTimes(ctx context.Context, db *sql.DB, org, repo, after, before string) ([]CycleTime, error) {
+	query := `SELECT org, repo, queued_at, done_at FROM (
+		SELECT g.org, g.repo,
+			(SELECT MIN(created_at) FROM goal_transitions WHERE goal_id = g.id AND to_status = 'queued') AS queued_at,
+			(SELECT MAX(created_at) FROM goal_transitions WHERE goal_id = g.id AND to_status = 'done') AS done_at
+		FROM goals g WHERE g.status = 'done'
+	) WHERE queued_at IS NOT NULL AND done_at IS NOT NULL`
+	var args []any
+	for _, c := range []struct{ cond, v string }{
+		{` AND org = ?`, org}, {` AND repo = ?`, repo},
+		{` AND done_at >= ?`, after}, {` AND done_at < ?`, before},
+	} {
+		if c.v != "" {
+			query += c.cond
+			args = append(args, c.v)
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, query+` ORDER BY org, repo`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var times []CycleTime
+	for rows.Next() {
+		var ct CycleTime
+		var queuedAt, doneAt string
+		if err := rows.Scan(&ct.Org, &ct.Repo, &queuedAt, &doneAt); err != nil {
+			return nil, err
+		}
+		start, err := time.Parse(timestampLayout, queuedAt)
+		if err != nil {
+			return nil, err
+		}
+		end, err := time.Parse(timestampLayout, doneAt)
+		if err != nil {
+			return nil, err
+		}
+		ct.Seconds = max(0, int64(end.Sub(start)/time.Second))
+		times = append(times, ct)
+	}
+	return times, rows.Err()
+}
+
+// GoalUpdate holds the editable fields of a goal; nil fields are left
+// unchanged. Model, Reasoning and ParentID point at the new value, so a
+// non-nil pointer to nil clears the field.
+type GoalUpdate struct {
+	Title     *string
+	Body      *string
+	Model     **string
+	Reasoning **string
+	ParentID  **int64
+}
+
+var (
+	errParentNotFound = errors.New("parent goal not found")
+	errParentCycle    = errors.New("parent would create a cycle")
+)
+
+// notEditableError reports that a goal's status forbids an edit. Model
+// and reasoning may only change before the goal runs; other fields until
+// it reaches a terminal status.
+type notEditableError struct {
+	Status           string
+	ModelOrReasoning bool
+}
+
+func (e *notEditableError) Error() string {
+	if e.ModelOrReasoning {
+		return "cannot change model or reasoning when goal is " + e.Status
+	}
+	return "cannot edit goal when it is " + e.Status
+}
+
+// updateGoal applies u in one transaction. A new parent is checked inside
+// it, so a concurrent re-parenting cannot slip a cycle in between the check
+// and the write: it fails with errParentNotFound or errParentCycle. The
+// UPDATE is conditioned on the goal's status, so a goal that moved on since
+// the caller read it fails with a *notEditableError.
+func updateGoal(ctx context.Context, db *sql.DB, id int64, u GoalUpdate) error {
+	now := timestamp(time.Now())
+	sets := []string{"updated_at = ?", "version = version + 1"}
+	args := []any{now}
+	if u.Title != nil {
+		sets = append(sets, "title = ?")
+		args = append(args, *u.Title)
+	}
+	if u.Body != nil {
+		sets = append(sets, "body = ?")
+		args = append(args, *u.Body)
+	}
+	if u.Model != nil {
+		sets = append(sets, "model = ?")
+		args = append(args, *u.Model)
+	}
+	if u.Reasoning != nil {
+		sets = append(sets, "reasoning = ?")
+		args = append(args, *u.Reasoning)
+	}
+	if u.ParentID != nil {
+		sets = append(sets, "parent_id = ?")
+		args = append(args, *u.ParentID)
+	}
+	args = append(args, id)
+	modelOrReasoning := u.Model != nil || u.Reasoning != nil
+	statusCond := ` AND status NOT IN ('done', 'cancelled')`
+	if modelOrReasoning {
+		statusCond = ` AND status IN ('draft', 'queued')`
+	}
+
+	return inTx(ctx, db, func(tx *sql.Tx) error {
+		if u.ParentID != nil && *u.ParentID != nil {
+			parentID := **u.ParentID
+			var exists int
+			err := tx.QueryRowContext(ctx, `SELECT 1 FROM goals WHERE id = ?`, parentID).Scan(&exists)
+			if err == sql.ErrNoRows {
+				return errParentNotFound
+			}
+			if err != nil {
+				return err
+			}
+			cycle, err := wouldCreateParentCycle(ctx, tx, id, parentID)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return errParentCycle
+			}
+		}
+		res, err := tx.ExecContext(ctx, `UPDATE goals SET `+strings.Join(sets, ", ")+` WHERE id = ?`+statusCond, args...)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return nil
+		}
+		var status string
+		if err := tx.QueryRowContext(ctx, `SELECT status FROM goals WHERE id = ?`, id).Scan(&status); err != nil {
+			return err
+		}
+		return &notEditableError{Status: status, ModelOrReasoning: modelOrReasoning}
+	})
+}
+
+// maxMetadataBytes caps the encoded size of a goal's metadata object.
+const maxMetadataBytes = 16 << 10
+
+// errMetadataTooLarge is returned when merging would push a goal's metadata
+// past maxMetadataBytes.
+var errMetadataTooLarge = errors.New("metadata exceeds " + strconv.Itoa(maxMetadataBytes) + " bytes")
+
+// nullableJSON stores an absent JSON value as NULL rather than "".
+func nullableJSON(v json.RawMessage) any {
+	if len(v) == 0 {
+		return nil
+	}
+	return string(v)
+}
+
+// mergeGoalMetadata merges patch into a goal's metadata object: each key
+// replaces the stored one, and a null value removes it. It returns the
+// merged object.
+func mergeGoalMetadata(ctx context.Context, db *sql.DB, id int64, patch map[string]json.RawMessage) (json.RawMessage, error) {
+	var merged json.RawMessage
+	err := inTx(ctx, db, func(tx *sql.Tx) error {
+		var current sql.NullString
+		if err := tx.QueryRowContext(ctx, `SELECT metadata FROM goals WHERE id = ?`, id).Scan(&current); err != nil {
+			return err
+		}
+		m := map[string]json.RawMessage{}
+		if current.Valid {
+			if err := json.Unmarshal([]byte(current.String), &m); err != nil {
+				return err
+			}
+		}
+		for k, v := range patch {
+			if string(v) == "null" {
+				delete(m, k)
+			} else {
+				m[k] = v
+			}
+		}
+		var err error
+		merged, err = json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		if len(merged) > maxMetadataBytes {
+			return errMetadataTooLarge
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE goals SET metadata = ?, updated_at = ?, version = version + 1 WHERE id = ?`,
+			string(merged), timestamp(time.Now()), id,
+		)
+		return err
+	})
+	return merged, err
+}
+
+func updateGoalPriority(ctx context.Context, db *sql.DB, id int64, priority *int) error {
+	now := timestamp(time.Now())
+	res, err := db.ExecContext(ctx,
+		`UPDATE goals SET priority = ?, updated_at = ?, version = version + 1 WHERE id = ?`,
+		priority, now, id,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func deleteGoal(ctx context.Context, db *sql.DB, id int64) error {
+	return inTx(ctx, db, func(tx *sql.Tx) error {
+		stmts := []string{
+			`DELETE FROM goal_comments WHERE goal_id = ?`,
+			`DELETE FROM goal_transitions WHERE goal_id = ?`,
+			`DELETE FROM goal_attachments WHERE goal_id = ?`,
+			`DELETE FROM goal_tags WHERE goal_id = ?`,
+			`DELETE FROM goal_dependencies WHERE goal_id = ?1 OR depends_on_id = ?1`,
+			`UPDATE goals SET parent_id = NULL, version = version + 1 WHERE parent_id = ?`,
+		}
+		for _, s := range stmts {
+			if _, err := tx.ExecContext(ctx, s, id); err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM goals WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+func updateGoalStatus(ctx context.Context, db *sql.DB, id int64, from, to string) error {
+	return transitionGoal(ctx, db, id, from, to, 0, nil)
+}
+
+// transitionGoal moves a goal from one status to another, storing note (if
+// any) on the recorded transition. A non-zero version makes the change
+// conditional on the goal still being at that version.
+func transitionGoal(ctx context.Context, db *sql.DB, id int64, from, to string, version int64, note *string) error {
+	return inTxNotify(ctx, db, func(tx *sql.Tx) error {
+		return setGoalStatus(ctx, tx, id, from, to, version, note)
+	})
+}
+
+// markGoalStuck moves a goal from from to stuck, recording why. A nil reason
+// leaves stuck_reason empty.
+func markGoalStuck(ctx context.Context, db *sql.DB, id int64, from string, version int64, reason, note *string) error {
+	return inTxNotify(ctx, db, func(tx *sql.Tx) error {
+		if err := setGoalStatus(ctx, tx, id, from, "stuck", version, note); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE goals SET stuck_reason = ? WHERE id = ?`, reason, id)
+		return err
+	})
+}
+
+// setGoalStatus moves a goal from one status to another within tx and
+// records the transition with note. It returns sql.ErrNoRows if the goal is
+// not in from, or errGoalModified if version is non-zero and no longer
+// current.
+func setGoalStatus(ctx context.Context, tx *sql.Tx, id int64, from, to string, version int64, note *string) error {
+	now := timestamp(time.Now())
+	cond, args := versionCondition(version)
+	res, err := tx.ExecContext(ctx,
+		`UPDATE goals SET status = ?, lease_expires_at = NULL, stuck_reason = NULL, updated_at = ?, version = version + 1 WHERE id = ? AND status = ?`+cond,
+		append([]any{to, now, id, from}, args...)...,
+	)
+	if err != nil {
+		return err
+	}
+	if err := checkUpdated(res, version); err != nil {
+		return err
+	}
+
+	return recordTransition(ctx, tx, id, from, to, now, note)
+}
+
+// errGoalModified is returned by a conditional write when the goal is no
+// longer at the version the caller read.
+var errGoalModified = errors.New("goal was modified")
+
+// versionCondition returns the WHERE clause fragment and argument that make
+// an UPDATE of goals conditional on version. Zero means unconditional.
+func versionCondition(version int64) (string, []any) {
+	if version == 0 {
+		return "", nil
+	}
+	return " AND version = ?", []any{version}
+}
+
+// checkUpdated maps an UPDATE guarded by versionCondition that matched no
+// rows to sql.ErrNoRows, or to errGoalModified when it was conditional.
+func checkUpdated(res sql.Result, version int64) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	if version != 0 {
+		return errGoalModified
+	}
+	return sql.ErrNoRows
+}
+
+// recordTransition appends a status change to the goal's history and, when
+// webhooks are enabled, queues its delivery in the outbox. Both happen in
+// tx, so an event is queued exactly when the change commits. Callers run tx
+// under inTxNotify, which wakes change-feed waiters after the commit.
+func recordTransition(ctx context.Context, tx *sql.Tx, id int64, from, to, ts string, note *string) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO goal_transitions (goal_id, from_status, to_status, note, created_at) VALUES (?, ?, ?, ?, ?)`,
+		id, from, to, note, ts,
+	)
+	if err != nil {
+		return err
+	}
+	if webhookURL == "" {
+		return nil
+	}
+
+	ev := statusEvent{ID: id, From: &from, To: to, TS: ts}
+	if err := tx.QueryRowContext(ctx, `SELECT org, repo FROM goals WHERE id = ?`, id).Scan(&ev.Org, &ev.Repo); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO outbox (url, payload, next_attempt_at, created_at) VALUES (?, ?, ?, ?)`,
+		webhookURL, string(payload), ts, ts,
+	)
+	return err
+}
+
+// cancelGoalCascade cancels a goal along with every non-terminal goal that
+// transitively depends on it, storing note on the goal's own transition and
+// explaining each cascaded one with a comment and transition note. It
+// returns the ids of the cascaded goals.
+func cancelGoalCascade(ctx context.Context, db *sql.DB, id int64, from string, version int64, note *string) ([]int64, error) {
+	type dependent struct {
+		id     int64
+		status string
+	}
+	var ids []int64
+	err := inTxNotify(ctx, db, func(tx *sql.Tx) error {
+		if err := setGoalStatus(ctx, tx, id, from, "cancelled", version, note); err != nil {
+			return err
+		}
+
+		// Only walk through goals that are themselves being cancelled; a finished
+		// dependent no longer blocks anything downstream of it.
+		rows, err := tx.QueryContext(ctx,
+			`WITH RECURSIVE doomed(id) AS (
+				SELECT gd.goal_id FROM goal_dependencies gd
+				JOIN goals g ON g.id = gd.goal_id
+				WHERE gd.depends_on_id = ? AND g.status NOT IN ('done','cancelled')
+				UNION
+				SELECT gd.goal_id FROM goal_dependencies gd
+				JOIN doomed d ON gd.depends_on_id = d.id
+				JOIN goals g ON g.id = gd.goal_id
+				WHERE g.status NOT IN ('done','cancelled')
+			)
+			SELECT g.id, g.status FROM goals g JOIN doomed ON doomed.id = g.id ORDER BY g.id`,
+			id,
+		)
+		if err != nil {
+			return err
+		}
+		var dependents []dependent
+		for rows.Next() {
+			var d dependent
+			if err := rows.Scan(&d.id, &d.status); err != nil {
+				rows.Close()
+				return err
+			}
+			dependents = append(dependents, d)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		comment := "Cancelled because dependency #" + strconv.FormatInt(id, 10) + " was cancelled"
+		now := timestamp(time.Now())
+		ids = []int64{}
+		for _, d := range dependents {
+			if err := setGoalStatus(ctx, tx, d.id, d.status, "cancelled", 0, &comment); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO goal_comments (goal_id, body, created_at) VALUES (?, ?, ?)`, d.id, comment, now); err != nil {
+				return err
+			}
+			ids = append(ids, d.id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// claimNextGoal atomically moves the highest-priority, oldest ready queued
+// goal matching org/repo to running, leasing it for the given duration.
+// The claim is a single UPDATE guarded on status, so concurrent callers can
+// never claim the same goal.
+func claimNextGoal(ctx context.Context, db *sql.DB, org, repo string, lease time.Duration) (*Goal, error) {
+	now := time.Now().UTC()
+	f := GoalFilter{Statuses: []string{"queued"}, Org: org, Repo: repo, Ready: true}
+	whereClause, args := f.where()
+
+	var id int64
+	err := inTxNotify(ctx, db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`UPDATE goals SET status = 'running', lease_expires_at = ?, updated_at = ?, version = version + 1
+			 WHERE status = 'queued' AND id = (SELECT id FROM goals `+whereClause+f.orderBy()+` LIMIT 1)
+			 RETURNING id`,
+			append([]any{timestamp(now.Add(lease)), timestamp(now)}, args...)...,
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		return recordTransition(ctx, tx, id, "queued", "running", timestamp(now), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return getGoal(ctx, db, id)
+}
+
+// extendLease pushes a running goal's lease out to now+lease.
+func extendLease(ctx context.Context, db *sql.DB, id int64, lease time.Duration) (string, error) {
+	expires := timestamp(time.Now().Add(lease))
+	res, err := db.ExecContext(ctx,
+		`UPDATE goals SET lease_expires_at = ? WHERE id = ? AND status = 'running'`,
+		expires, id,
+	)
+	if err != nil {
+		return "", err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", sql.ErrNoRows
+	}
+	return expires, nil
+}
+
+// leaseRetriesReason is the stuck_reason (and transition note) given to a
+// goal whose lease expired after it had used up its retries.
+const leaseRetriesReason = "auto-stuck: lease expired with no retries left"
+
+// requeueExpiredLeases moves running goals whose lease expired before now
+// back to queued, counting the lost run as a retry. Goals already retried
+// limit times go to stuck instead, as requeueing them would exceed the cap.
+// Goals started without a lease are left alone.
+func requeueExpiredLeases(ctx context.Context, db *sql.DB, now time.Time, limit int) (requeued, stuck []int64, err error) {
+	ts := timestamp(now)
+	expire := func(tx *sql.Tx, query string, args ...any) ([]int64, error) {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		return ids, rows.Err()
+	}
+	err = inTxNotify(ctx, db, func(tx *sql.Tx) error {
+		var err error
+		stuck, err = expire(tx,
+			`UPDATE goals SET status = 'stuck', stuck_reason = ?, lease_expires_at = NULL, updated_at = ?, version = version + 1
+			 WHERE status = 'running' AND lease_expires_at IS NOT NULL AND lease_expires_at < ? AND retries >= ?
+			 RETURNING id`,
+			leaseRetriesReason, ts, ts, limit,
+		)
+		if err != nil {
+			return err
+		}
+		requeued, err = expire(tx,
+			`UPDATE goals SET status = 'queued', retries = retries + 1, lease_expires_at = NULL, updated_at = ?, version = version + 1
+			 WHERE status = 'running' AND lease_expires_at IS NOT NULL AND lease_expires_at < ?
+			 RETURNING id`,
+			ts, ts,
+		)
+		if err != nil {
+			return err
+		}
+
+		note := leaseRetriesReason
+		for _, id := range stuck {
+			if err := recordTransition(ctx, tx, id, "running", "stuck", ts, &note); err != nil {
+				return err
+			}
+		}
+		for _, id := range requeued {
+			if err := recordTransition(ctx, tx, id, "running", "queued", ts, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return requeued, stuck, nil
+}
+
+// staleDraftNote is recorded on the transition when a draft is cancelled
+// for age.
+const staleDraftNote = "auto-cancelled: stale draft"
+
+// cancelStaleDrafts cancels draft goals created before cutoff. Goals that
+// have been queued are never touched, even if they later return to stuck.
+func cancelStaleDrafts(ctx context.Context, db *sql.DB, cutoff, now time.Time) ([]int64, error) {
+	ts := timestamp(now)
+	var ids []int64
+	err := inTxNotify(ctx, db, func(tx *sql.Tx) error {
+		ids = nil
+		rows, err := tx.QueryContext(ctx,
+			`UPDATE goals SET status = 'cancelled', updated_at = ?, version = version + 1
+			 WHERE status = 'draft' AND created_at < ?
+			 RETURNING id`,
+			ts, timestamp(cutoff),
+		)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		note := staleDraftNote
+		for _, id := range ids {
+			if err := recordTransition(ctx, tx, id, "draft", "cancelled", ts, &note); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// runningTimeoutReason is the stuck_reason (and transition note) given to a
+// running goal that went too long without an update.
+const runningTimeoutReason = "auto-stuck: no update while running"
+
+// stickIdleRunningGoals moves running goals not updated since cutoff to
+// stuck. Leased goals are left to the lease sweeper, since heartbeats extend
+// the lease without touching updated_at.
+func stickIdleRunningGoals(ctx context.Context, db *sql.DB, cutoff, now time.Time) ([]int64, error) {
+	ts := timestamp(now)
+	var ids []int64
+	err := inTxNotify(ctx, db, func(tx *sql.Tx) error {
+		ids = nil
+		rows, err := tx.QueryContext(ctx,
+			`UPDATE goals SET status = 'stuck', stuck_reason = ?, updated_at = ?, version = version + 1
+			 WHERE status = 'running' AND lease_expires_at IS NULL AND updated_at < ?
+			 RETURNING id`,
+			runningTimeoutReason, ts, timestamp(cutoff),
+		)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		note := runningTimeoutReason
+		for _, id := range ids {
+			if err := recordTransition(ctx, tx, id, "running", "stuck", ts, &note); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// requeueStuckGoals moves every stuck goal in org/repo (empty matches any)
+// back to queued in one transaction, incrementing retries. Goals that have
+// already been retried limit times are left stuck and returned as skipped.
+func requeueStuckGoals(ctx context.Context, db *sql.DB, org, repo string, limit int) (requeued, skipped []int64, err error) {
+	now := timestamp(time.Now())
+	where, args := GoalFilter{Statuses: []string{"stuck"}, Org: org, Repo: repo}.where()
+	err = inTxNotify(ctx, db, func(tx *sql.Tx) error {
+		requeued, skipped = nil, nil
+		rows, err := tx.QueryContext(ctx, `SELECT id, retries FROM goals `+where+` ORDER BY id`, args...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id int64
+			var retries int
+			if err := rows.Scan(&id, &retries); err != nil {
+				rows.Close()
+				return err
+			}
+			if retries >= limit {
+				skipped = append(skipped, id)
+			} else {
+				requeued = append(requeued, id)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range requeued {
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE goals SET status = 'queued', retries = retries + 1, stuck_reason = NULL, updated_at = ?, version = version + 1 WHERE id = ?`,
+				now, id,
+			); err != nil {
+				return err
+			}
+			if err := recordTransition(ctx, tx, id, "stuck", "queued", now, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return requeued, skipped, nil
+}
+
+// errRetryLimit is returned by requeueGoal for a goal already retried the
+// maximum number of times.
+var errRetryLimit = errors.New("goal has reached the retry limit")
+
+// requeueGoal moves a stuck goal back to queued and increments its retry
+// counter, returning the new count. A goal already retried limit times is
+// left stuck and errRetryLimit returned; the cap is part of the UPDATE so
+// concurrent requeues cannot both pass it. note is stored on the
+// transition, and a non-zero version makes the requeue conditional as in
+// setGoalStatus.
+func requeueGoal(ctx context.Context, db *sql.DB, id int64, limit int, version int64, note *string) (int, error) {
+	now := timestamp(time.Now())
+	var retries int
+	err := inTxNotify(ctx, db, func(tx *sql.Tx) error {
+		cond, args := versionCondition(version)
+		res, err := tx.ExecContext(ctx,
+			`UPDATE goals SET status = 'queued', retries = retries + 1, stuck_reason = NULL, updated_at = ?, version = version + 1 WHERE id = ? AND status = 'stuck' AND retries < ?`+cond,
+			append([]any{now, id, limit}, args...)...,
+		)
+		if err != nil {
+			return err
+		}
+		if err := checkUpdated(res, version); err != nil {
+			var status string
+			var retries int
+			if tx.QueryRowContext(ctx, `SELECT status, retries FROM goals WHERE id = ?`, id).Scan(&status, &retries) == nil &&
+				status == "stuck" && retries >= limit {
+				return errRetryLimit
+			}
+			return err
+		}
+
+		if err := recordTransition(ctx, tx, id, "stuck", "queued", now, note); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, `SELECT retries FROM goals WHERE id = ?`, id).Scan(&retries)
+	})
+	return retries, err
+}
+
+func listTransitions(ctx context.Context, db *sql.DB, goalID int64) ([]Transition, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, goal_id, from_status, to_status, note, created_at FROM goal_transitions WHERE goal_id = ? ORDER BY id`, goalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transitions []Transition
+	for rows.Next() {
+		var t Transition
+		if err := rows.Scan(&t.ID, &t.GoalID, &t.FromStatus, &t.ToStatus, &t.Note, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, rows.Err()
+}
+
+// listChanges returns up to limit transitions with id above since, in id
+// order, plus whether more follow.
+func listChanges(ctx context.Context, db *sql.DB, since int64, limit int) ([]Change, bool, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT t.id, t.goal_id, t.from_status, t.to_status, t.note, t.created_at, g.org, g.repo, g.status
+		FROM goal_transitions t JOIN goals g ON g.id = t.goal_id
+		WHERE t.id > ? ORDER BY t.id LIMIT ?`,
+		since, limit+1,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var changes []Change
+	for rows.Next() {
+		var c Change
+		if err := rows.Scan(&c.ID, &c.GoalID, &c.FromStatus, &c.ToStatus, &c.Note, &c.CreatedAt, &c.Org, &c.Repo, &c.GoalStatus); err != nil {
+			return nil, false, err
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	more := len(changes) > limit
+	if more {
+		changes = changes[:limit]
+	}
+	return changes, more, nil
+}
+
+// listActivity merges a goal's comments and transitions oldest first. Within
+// the same second, transitions sort before comments. A limit of 0 returns
+// everything; otherwise the total item count is returned too.
+func listActivity(ctx context.Context, db *sql.DB, goalID int64, limit, offset int) ([]ActivityItem, int, error) {
+	total := 0
+	if limit > 0 {
+		err := db.QueryRowContext(ctx,
+			`SELECT (SELECT COUNT(*) FROM goal_comments WHERE goal_id = ?) + (SELECT COUNT(*) FROM goal_transitions WHERE goal_id = ?)`,
+			goalID, goalID,
+		).Scan(&total)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	query := `SELECT 'comment' AS type, id, body, NULL, NULL, NULL, created_at FROM goal_comments WHERE goal_id = ?
+		UNION ALL
+		SELECT 'transition', id, NULL, from_status, to_status, note, created_at FROM goal_transitions WHERE goal_id = ?
+		ORDER BY created_at, type DESC, id`
+	args := []any{goalID, goalID}
+	if limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, offset)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var items []ActivityItem
+	for rows.Next() {
+		var a ActivityItem
+		if err := rows.Scan(&a.Type, &a.ID, &a.Body, &a.FromStatus, &a.ToStatus, &a.Note, &a.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, a)
+	}
+	return items, total, rows.Err()
+}
+
+func createComment(ctx context.Context, db *sql.DB, goalID int64, body string) (int64, error) {
+	res, err := db.ExecContext(ctx,
+		`INSERT INTO goal_comments (goal_id, body, created_at) VALUES (?, ?, ?)`,
+		goalID, body, timestamp(time.Now()),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func listComments(ctx context.Context, db *sql.DB, goalID int64) ([]Comment, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, goal_id, body, created_at FROM goal_comments WHERE goal_id = ? ORDER BY id`, goalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(&c.ID, &c.GoalID, &c.Body, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+func updateComment(ctx context.Context, db *sql.DB, goalID, commentID int64, body string) error {
+	res, err := db.ExecContext(ctx,
+		`UPDATE goal_comments SET body = ? WHERE id = ? AND goal_id = ?`,
+		body, commentID, goalID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func deleteComment(ctx context.Context, db *sql.DB, goalID, commentID int64) error {
+	res, err := db.ExecContext(ctx,
+		`DELETE FROM goal_comments WHERE id = ? AND goal_id = ?`,
+		commentID, goalID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx, for reads that must also
+// run inside a transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Errors identifying why a batch of dependencies was rejected.
+var (
+	errDependencyNotFound  = errors.New("dependency goal not found")
+	errDependencyCycle     = errors.New("dependency would create a cycle")
+	errDependencyCrossRepo = errors.New("dependency goal is in a different org/repo")
+)
+
+// dependencyError names the dependency that made addDependencies fail.
+type dependencyError struct {
+	DependsOnID int64
+	Err         error
+}
+
+func (e *dependencyError) Error() string {
+	return fmt.Sprintf("%v: %d", e.Err, e.DependsOnID)
+}
+
+func (e *dependencyError) Unwrap() error { return e.Err }
+
+// addDependencies adds an edge from goalID to each of dependsOnIDs in one
+// transaction. If any target is missing, in another org/repo (unless
+// allowCrossRepoDeps) or would close a cycle (counting the edges added
+// earlier in the batch), nothing is added and a *dependencyError names it.
+// Edges that already exist are left as they are.
+func addDependencies(ctx context.Context, db *sql.DB, goalID int64, dependsOnIDs []int64) error {
+	return inTx(ctx, db, func(tx *sql.Tx) error {
+		var org, repo string
+		if err := tx.QueryRowContext(ctx, `SELECT org, repo FROM goals WHERE id = ?`, goalID).Scan(&org, &repo); err != nil {
+			return err
+		}
+		for _, dep := range dependsOnIDs {
+			var depOrg, depRepo string
+			err := tx.QueryRowContext(ctx, `SELECT org, repo FROM goals WHERE id = ?`, dep).Scan(&depOrg, &depRepo)
+			if err == sql.ErrNoRows {
+				return &dependencyError{DependsOnID: dep, Err: errDependencyNotFound}
+			}
+			if err != nil {
+				return err
+			}
+			if !allowCrossRepoDeps && (depOrg != org || depRepo != repo) {
+				return &dependencyError{DependsOnID: dep, Err: errDependencyCrossRepo}
+			}
+			cycle, err := wouldCreateCycle(ctx, tx, goalID, dep)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return &dependencyError{DependsOnID: dep, Err: errDependencyCycle}
+			}
+			if _, err := tx.ExecContext(ctx,
+				`INSERT OR IGNORE INTO goal_dependencies (goal_id, depends_on_id) VALUES (?, ?)`,
+				goalID, dep,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func addDependency(ctx context.Context, db *sql.DB, goalID, dependsOnID int64) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO goal_dependencies (goal_id, depends_on_id) VALUES (?, ?)`,
+		goalID, dependsOnID,
+	)
+	return err
+}
+
+// wouldCreateCycle reports whether adding the edge goalID -> dependsOnID would
+// close a loop, i.e. whether goalID is already reachable from dependsOnID.
+func wouldCreateCycle(ctx context.Context, db queryer, goalID, dependsOnID int64) (bool, error) {
+	visited := map[int64]bool{}
+	stack := []int64{dependsOnID}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if cur == goalID {
+			return true, nil
+		}
+		if visited[cur] {
+			continue
+		}
+		visited[cur] = true
+
+		deps, err := listDependencies(ctx, db, cur)
+		if err != nil {
+			return false, err
+		}
+		stack = append(stack, deps...)
+	}
+	return false, nil
+}
+
+// wouldCreateParentCycle reports whether making parentID the parent of id
+// would put id in its own ancestry.
+func wouldCreateParentCycle(ctx context.Context, db queryer, id, parentID int64) (bool, error) {
+	visited := map[int64]bool{}
+	for cur := parentID; !visited[cur]; {
+		if cur == id {
+			return true, nil
+		}
+		visited[cur] = true
+		var next sql.NullInt64
+		err := db.QueryRowContext(ctx, `SELECT parent_id FROM goals WHERE id = ?`, cur).Scan(&next)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !next.Valid {
+			return false, nil
+		}
+		cur = next.Int64
+	}
+	return false, nil
+}
+
+// dependencyGraph returns the goals with the given status in claim order
+// (highest priority first, then oldest) together with the dependency edges
+// between them, keyed by dependent goal.
+func dependencyGraph(ctx context.Context, db *sql.DB, status string) ([]GoalSummary, map[int64][]int64, error) {
+	goals, err := queryGoalSummaries(ctx, db,
+		`SELECT `+goalSummaryColumns+` FROM goals WHERE status = ? ORDER BY priority DESC, id ASC`, status,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT gd.goal_id, gd.depends_on_id FROM goal_dependencies gd
+		JOIN goals a ON a.id = gd.goal_id
+		JOIN goals b ON b.id = gd.depends_on_id
+		WHERE a.status = ?1 AND b.status = ?1
+		ORDER BY gd.goal_id, gd.depends_on_id`, status,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	deps := map[int64][]int64{}
+	for rows.Next() {
+		var goalID, dependsOnID int64
+		if err := rows.Scan(&goalID, &dependsOnID); err != nil {
+			return nil, nil, err
+		}
+		deps[goalID] = append(deps[goalID], dependsOnID)
+	}
+	return goals, deps, rows.Err()
+}
+
+// Import modes decide what happens when an imported goal's id is taken.
+const (
+	importError   = "error"   // fail the whole import
+	importSkip    = "skip"    // keep the existing goal
+	importReplace = "replace" // overwrite it in place
+)
+
+// ImportResult lists the goals an import created or replaced (by their id
+// in this database) and the ids it skipped.
+type ImportResult struct {
+	Imported []int64 `json:"imported"`
+	Skipped  []int64 `json:"skipped"`
+}
+
+// importConflictError reports an id already in use under importError mode.
+type importConflictError struct{ ID int64 }
+
+func (e *importConflictError) Error() string {
+	return fmt.Sprintf("goal %d already exists", e.ID)
+}
+
+// invalidImportError reports a goal in the dump that can't be imported.
+type invalidImportError struct {
+	ID  int64
+	Msg string
+}
+
+func (e *invalidImportError) Error() string {
+	return fmt.Sprintf("goal %d: %s", e.ID, e.Msg)
+}
+
+// validTimestamp reports whether ts is exactly in timestampLayout. Stored
+// timestamps are compared as strings and parsed by the timing reports, so
+// anything else, even an equivalent time, would break them.
+func validTimestamp(ts string) bool {
+	t, err := time.Parse(timestampLayout, ts)
+	return err == nil && timestamp(t) == ts
+}
+
+// importTimestampError checks every timestamp in e, describing the first
+// invalid one, or returns "" if all are valid.
+func importTimestampError(e GoalExport) string {
+	g := e.Goal
+	fields := [][2]string{{"created_at", g.CreatedAt}, {"updated_at", g.UpdatedAt}}
+	if g.LeaseExpiresAt != nil {
+		fields = append(fields, [2]string{"lease_expires_at", *g.LeaseExpiresAt})
+	}
+	for _, f := range fields {
+		if !validTimestamp(f[1]) {
+			return "invalid " + f[0] + " " + strconv.Quote(f[1])
+		}
+	}
+	for _, c := range e.Comments {
+		if !validTimestamp(c.CreatedAt) {
+			return "invalid comment created_at " + strconv.Quote(c.CreatedAt)
+		}
+	}
+	for _, t := range e.Transitions {
+		if !validTimestamp(t.CreatedAt) {
+			return "invalid transition created_at " + strconv.Quote(t.CreatedAt)
+		}
+	}
+	for _, a := range e.Attachments {
+		if !validTimestamp(a.CreatedAt) || !validTimestamp(a.UpdatedAt) {
+			return "invalid timestamp on attachment " + strconv.Quote(a.Name)
+		}
+	}
+	return ""
+}
+
+// importGoals recreates exported goals with their comments, transitions,
+// tags, dependencies and parents in one transaction. With preserveIDs each
+// goal keeps its id and mode settles conflicts; otherwise goals get new ids
+// and references between them are remapped. Dependencies and parents are
+// checked for cycles, so a failed check rolls back everything.
+func importGoals(ctx context.Context, db *sql.DB, goals []GoalExport, preserveIDs bool, mode string) (*ImportResult, error) {
+	seen := map[int64]bool{}
+	for _, e := range goals {
+		g := e.Goal
+		if g == nil {
+			return nil, &invalidImportError{Msg: "missing goal"}
+		}
+		switch {
+		case seen[g.ID]:
+			return nil, &invalidImportError{g.ID, "appears more than once"}
+		case !slices.Contains(statuses, g.Status):
+			return nil, &invalidImportError{g.ID, "invalid status " + strconv.Quote(g.Status)}
+		case g.Org == "" || g.Repo == "" || g.Title == "" || g.Body == "":
+			return nil, &invalidImportError{g.ID, "org, repo, title, and body are required"}
+		case g.Model != nil && !validModels[*g.Model]:
+			return nil, &invalidImportError{g.ID, "invalid model " + strconv.Quote(*g.Model)}
+		case g.Reasoning != nil && !validReasoning[*g.Reasoning]:
+			return nil, &invalidImportError{g.ID, "invalid reasoning " + strconv.Quote(*g.Reasoning)}
+		case g.Priority != nil && !validPriority(*g.Priority):
+			return nil, &invalidImportError{g.ID, "priority must be between 0 and 100"}
+		}
+		if msg := importTimestampError(e); msg != "" {
+			return nil, &invalidImportError{g.ID, msg}
+		}
+		metadata, msg := normalizeMetadata(g.Metadata)
+		if msg != "" {
+			return nil, &invalidImportError{g.ID, msg}
+		}
+		g.Metadata = metadata
+		names := map[string]bool{}
+		for _, a := range e.Attachments {
+			if a.Name == "" || names[a.Name] {
+				return nil, &invalidImportError{g.ID, "attachment names must be present and unique"}
+			}
+			names[a.Name] = true
+		}
+		seen[g.ID] = true
+	}
+
+	var result ImportResult
+	err := inTxNotify(ctx, db, func(tx *sql.Tx) error {
+		result = ImportResult{Imported: []int64{}, Skipped: []int64{}}
+		// ids maps each imported goal's exported id to its id here
+		ids := map[int64]int64{}
+		for _, e := range goals {
+			id, skip, err := importGoal(ctx, tx, e, preserveIDs, mode)
+			if err != nil {
+				return err
+			}
+			if skip {
+				result.Skipped = append(result.Skipped, e.Goal.ID)
+				continue
+			}
+			ids[e.Goal.ID] = id
+			result.Imported = append(result.Imported, id)
+		}
+
+		// resolve finds a referenced goal among those imported, or (when ids
+		// are preserved) already in the database
+		resolve := func(from, ref int64) (int64, error) {
+			if id, ok := ids[ref]; ok {
+				return id, nil
+			}
+			var exists bool
+			if preserveIDs {
+				if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM goals WHERE id = ?)`, ref).Scan(&exists); err != nil {
+					return 0, err
+				}
+			}
+			if !exists {
+				return 0, &invalidImportError{from, fmt.Sprintf("references missing goal %d", ref)}
+			}
+			return ref, nil
+		}
+		for _, e := range goals {
+			id, ok := ids[e.Goal.ID]
+			if !ok {
+				continue
+			}
+			if e.Goal.ParentID != nil {
+				parent, err := resolve(e.Goal.ID, *e.Goal.ParentID)
+				if err != nil {
+					return err
+				}
+				if cycle, err := wouldCreateParentCycle(ctx, tx, id, parent); err != nil {
+					return err
+				} else if cycle {
+					return &invalidImportError{e.Goal.ID, "parent would create a cycle"}
+				}
+				if _, err := tx.ExecContext(ctx, `UPDATE goals SET parent_id = ? WHERE id = ?`, parent, id); err != nil {
+					return err
+				}
+			}
+			for _, ref := range e.Dependencies {
+				dep, err := resolve(e.Goal.ID, ref)
+				if err != nil {
+					return err
+				}
+				if cycle, err := wouldCreateCycle(ctx, tx, id, dep); err != nil {
+					return err
+				} else if cycle {
+					return &invalidImportError{e.Goal.ID, fmt.Sprintf("dependency on goal %d would create a cycle", ref)}
+				}
+				if _, err := tx.ExecContext(ctx,
+					`INSERT OR IGNORE INTO goal_dependencies (goal_id, depends_on_id) VALUES (?, ?)`, id, dep,
+				); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// importGoal writes one goal with its comments, transitions, tags and
+// attachments, leaving its parent and dependencies to the caller. It returns
+// the goal's id here, or skip when mode left an existing goal in place.
+func importGoal(ctx context.Context, tx *sql.Tx, e GoalExport, preserveIDs bool, mode string) (id int64, skip bool, err error) {
+	g := e.Goal
+	metadata := g.Metadata
+	if string(metadata) == "null" {
+		metadata = nil
+	}
+	cols := []any{g.Org, g.Repo, g.Title, g.Body, g.Status, g.Retries, g.Model, g.Reasoning, g.Priority, g.LeaseExpiresAt, g.StuckReason, nullableJSON(metadata), g.CreatedAt, g.UpdatedAt}
+
+	exists := false
+	if preserveIDs {
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM goals WHERE id = ?)`, g.ID).Scan(&exists); err != nil {
+			return 0, false, err
+		}
+	}
+	switch {
+	case exists && mode == importSkip:
+		return 0, true, nil
+	case exists && mode == importReplace:
+		// Replaced in place, so other goals' dependencies on it and its
+		// children survive
+		for _, stmt := range []string{
+			`DELETE FROM goal_comments WHERE goal_id = ?`,
+			`DELETE FROM goal_transitions WHERE goal_id = ?`,
+			`DELETE FROM goal_tags WHERE goal_id = ?`,
+			`DELETE FROM goal_dependencies WHERE goal_id = ?`,
+			`DELETE FROM goal_attachments WHERE goal_id = ?`,
+		} {
+			if _, err := tx.ExecContext(ctx, stmt, g.ID); err != nil {
+				return 0, false, err
+			}
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE goals SET org = ?, repo = ?, title = ?, body = ?, status = ?, retries = ?, model = ?, reasoning = ?, priority = ?, lease_expires_at = ?, stuck_reason = ?, metadata = ?, created_at = ?, updated_at = ?, parent_id = NULL, version = MAX(version + 1, ?) WHERE id = ?`,
+			append(cols, g.Version, g.ID)...,
+		); err != nil {
+			return 0, false, err
+		}
+		id = g.ID
+	case exists:
+		return 0, false, &importConflictError{g.ID}
+	default:
+		// Exports written before goals had a version carry none
+		cols = append(cols, max(g.Version, 1))
+		query := `INSERT INTO goals (org, repo, title, body, status, retries, model, reasoning, priority, lease_expires_at, stuck_reason, metadata, created_at, updated_at, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		if preserveIDs {
+			query = `INSERT INTO goals (org, repo, title, body, status, retries, model, reasoning, priority, lease_expires_at, stuck_reason, metadata, created_at, updated_at, version, id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			cols = append(cols, g.ID)
+		}
+		res, err := tx.ExecContext(ctx, query, cols...)
+		if err != nil {
+			return 0, false, err
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return 0, false, err
+		}
+	}
+
+	for _, c := range e.Comments {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO goal_comments (goal_id, body, created_at) VALUES (?, ?, ?)`, id, c.Body, c.CreatedAt,
+		); err != nil {
+			return 0, false, err
+		}
+	}
+	for _, t := range e.Transitions {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO goal_transitions (goal_id, from_status, to_status, note, created_at) VALUES (?, ?, ?, ?, ?)`,
+			id, t.FromStatus, t.ToStatus, t.Note, t.CreatedAt,
+		); err != nil {
+			return 0, false, err
+		}
+	}
+	for _, tag := range e.Tags {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO goal_tags (goal_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+			return 0, false, err
+		}
+	}
+	for _, a := range e.Attachments {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO goal_attachments (goal_id, name, body, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+			id, a.Name, a.Body, a.CreatedAt, a.UpdatedAt,
+		); err != nil {
+			return 0, false, err
+		}
+	}
+	return id, false, nil
+}
+
+// goalIDsAfter returns up to limit goal ids above afterID in ascending order,
+// for walking every goal in batches.
+func goalIDsAfter(ctx context.Context, db *sql.DB, afterID int64, limit int) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id FROM goals WHERE id > ? ORDER BY id LIMIT ?`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// goalsAfter returns up to limit goals matching f with id above afterID, in
+// id order, for callers that page through every match.
+func goalsAfter(ctx context.Context, db *sql.DB, f GoalFilter, afterID int64, limit int) ([]Goal, error) {
+	whereClause, args := f.where()
+	rows, err := db.QueryContext(ctx,
+		`SELECT `+goalColumns+` FROM goals `+whereClause+` AND id > ? ORDER BY id LIMIT ?`,
+		append(args, afterID, limit)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []Goal
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, *g)
+	}
+	return goals, rows.Err()
+}
+
+// listChildren returns the goals whose parent is parentID, oldest first.
+func listChildren(ctx context.Context, db *sql.DB, parentID int64) ([]GoalSummary, error) {
+	return queryGoalSummaries(ctx, db,
+		`SELECT `+goalSummaryColumns+` FROM goals WHERE parent_id = ? ORDER BY id`, parentID,
+	)
+}
+
+func removeDependency(ctx context.Context, db *sql.DB, goalID, dependsOnID int64) error {
+	res, err := db.ExecContext(ctx,
+		`DELETE FROM goal_dependencies WHERE goal_id = ? AND depends_on_id = ?`,
+		goalID, dependsOnID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func listDependencies(ctx context.Context, db queryer, goalID int64) ([]int64, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT depends_on_id FROM goal_dependencies WHERE goal_id = ? ORDER BY depends_on_id`,
+		goalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// LinkedGoal is a goal on the other end of a dependency, with its status.
+type LinkedGoal struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+// listLinkedGoals returns a page of the goals goalID depends on, or with
+// dependents the goals depending on it, in id order. unmetOnly keeps just the
+// dependencies that still block goalID. As with listGoals a limit of 0
+// returns every goal and skips counting the total.
+func listLinkedGoals(ctx context.Context, db *sql.DB, goalID int64, dependents, unmetOnly bool, limit, offset int) ([]LinkedGoal, int, error) {
+	// near is the column holding goalID, far the one naming the linked goal
+	near, far := "d.goal_id", "d.depends_on_id"
+	if dependents {
+		near, far = far, near
+	}
+	from := ` FROM goal_dependencies d JOIN goals g ON g.id = ` + far + ` WHERE ` + near + ` = ?`
+	if unmetOnly {
+		from += ` AND ` + unmetDependencyCondition("g")
+	}
+	args := []any{goalID}
+
+	total := 0
+	if limit > 0 {
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*)`+from, args...).Scan(&total); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	query := `SELECT g.id, g.status` + from + ` ORDER BY g.id`
+	if limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, offset)
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var goals []LinkedGoal
+	for rows.Next() {
+		var g LinkedGoal
+		if err := rows.Scan(&g.ID, &g.Status); err != nil {
+			return nil, 0, err
+		}
+		goals = append(goals, g)
+	}
+	return goals, total, rows.Err()
+}
+
+func addTag(ctx context.Context, db *sql.DB, goalID int64, tag string) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO goal_tags (goal_id, tag) VALUES (?, ?)`,
+		goalID, tag,
+	)
+	return err
+}
+
+func removeTag(ctx context.Context, db *sql.DB, goalID int64, tag string) error {
+	res, err := db.ExecContext(ctx,
+		`DELETE FROM goal_tags WHERE goal_id = ? AND tag = ?`,
+		goalID, tag,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func listTags(ctx context.Context, db *sql.DB, goalID int64) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT tag FROM goal_tags WHERE goal_id = ? ORDER BY tag`,
+		goalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func createAttachment(ctx context.Context, db *sql.DB, goalID int64, name, body string) (int64, error) {
+	now := timestamp(time.Now())
+	res, err := db.ExecContext(ctx,
+		`INSERT INTO goal_attachments (goal_id, name, body, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		goalID, name, body, now, now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func getAttachment(ctx context.Context, db *sql.DB, id int64) (*Attachment, error) {
+	row := db.QueryRowContext(ctx,
+		`SELECT id, goal_id, name, body, created_at, updated_at FROM goal_attachments WHERE id = ?`, id,
+	)
+	var a Attachment
+	err := row.Scan(&a.ID, &a.GoalID, &a.Name, &a.Body, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func listAttachments(ctx context.Context, db *sql.DB, goalID int64) ([]AttachmentSummary, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, goal_id, name, created_at, updated_at FROM goal_attachments WHERE goal_id = ? ORDER BY id`, goalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []AttachmentSummary
+	for rows.Next() {
+		var a AttachmentSummary
+		if err := rows.Scan(&a.ID, &a.GoalID, &a.Name, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// listAttachmentBodies is listAttachments including each body, for export.
+func listAttachmentBodies(ctx context.Context, db *sql.DB, goalID int64) ([]Attachment, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, goal_id, name, body, created_at, updated_at FROM goal_attachments WHERE goal_id = ? ORDER BY id`, goalID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []Attachment
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.ID, &a.GoalID, &a.Name, &a.Body, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+func editAttachmentBody(ctx context.Context, db *sql.DB, id int64, newBody string) error {
+	now := timestamp(time.Now())
+	res, err := db.ExecContext(ctx,
+		`UPDATE goal_attachments SET body = ?, updated_at = ? WHERE id = ?`,
+		newBody, now, id,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
 	}
 	return nil
 }
 
-func deleteAttachment(db *sql.DB, id int64) error {
-	res, err := db.Exec(`DELETE FROM goal_attachments WHERE id = ?`, id)
+func deleteAttachment(ctx context.Context, db *sql.DB, id int64) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM goal_attachments WHERE id = ?`, id)
 	if err != nil {
 		return err
 	}
@@ -510,12 +2541,43 @@ func deleteAttachment(db *sql.DB, id int64) error {
 	return nil
 }
 
-func hasUnmetDependencies(db *sql.DB, goalID int64) (bool, error) {
+func hasUnmetDependencies(ctx context.Context, db *sql.DB, goalID int64) (bool, error) {
+	count, err := countUnmetDependencies(ctx, db, goalID)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func countUnmetDependencies(ctx context.Context, db *sql.DB, goalID int64) (int, error) {
 	var count int
-	err := db.QueryRow(
+	err := db.QueryRowContext(ctx,
 		`SELECT COUNT(*) FROM goal_dependencies gd
 		 JOIN goals g ON g.id = gd.depends_on_id
-		 WHERE gd.goal_id = ? AND g.status != 'done'`,
+		 WHERE gd.goal_id = ? AND `+unmetDependencyCondition("g"),
+		goalID,
+	).Scan(&count)
+	return count, err
+}
+
+// cancelledDependency returns the id of a cancelled dependency of the goal,
+// or sql.ErrNoRows if it has none.
+func cancelledDependency(ctx context.Context, db *sql.DB, goalID int64) (int64, error) {
+	var id int64
+	err := db.QueryRowContext(ctx,
+		`SELECT g.id FROM goal_dependencies gd
+		 JOIN goals g ON g.id = gd.depends_on_id
+		 WHERE gd.goal_id = ? AND g.status = 'cancelled'
+		 ORDER BY g.id LIMIT 1`,
+		goalID,
+	).Scan(&id)
+	return id, err
+}
+
+func hasDependents(ctx context.Context, db *sql.DB, goalID int64) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM goal_dependencies WHERE depends_on_id = ?`,
 		goalID,
 	).Scan(&count)
 	if err != nil {
@@ -523,3 +2585,123 @@ func hasUnmetDependencies(db *sql.DB, goalID int64) (bool, error) {
 	}
 	return count > 0, nil
 }
+
+// pendingOutbox returns up to limit undelivered outbox entries due at or
+// before now, oldest first.
+func pendingOutbox(ctx context.Context, db *sql.DB, now time.Time, limit int) ([]OutboxEntry, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, url, payload, attempts, next_attempt_at FROM outbox
+		 WHERE delivered_at IS NULL AND next_attempt_at <= ?
+		 ORDER BY id LIMIT ?`,
+		timestamp(now), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []OutboxEntry
+	for rows.Next() {
+		var e OutboxEntry
+		if err := rows.Scan(&e.ID, &e.URL, &e.Payload, &e.Attempts, &e.NextAttemptAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func markOutboxDelivered(ctx context.Context, db *sql.DB, id int64, now time.Time) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE outbox SET attempts = attempts + 1, delivered_at = ? WHERE id = ?`,
+		timestamp(now), id,
+	)
+	return err
+}
+
+// rescheduleOutbox records a failed delivery attempt and sets when to retry.
+func rescheduleOutbox(ctx context.Context, db *sql.DB, id int64, next time.Time) error {
+	_, err := db.ExecContext(ctx,
+		`UPDATE outbox SET attempts = attempts + 1, next_attempt_at = ? WHERE id = ?`,
+		timestamp(next), id,
+	)
+	return err
+}
+
+// getOrgDefaults returns sql.ErrNoRows when org has no defaults set.
+func getOrgDefaults(ctx context.Context, db *sql.DB, org string) (*OrgDefaults, error) {
+	row := db.QueryRowContext(ctx,
+		`SELECT org, model, reasoning, updated_at FROM org_defaults WHERE org = ?`, org,
+	)
+	var d OrgDefaults
+	var model, reasoning sql.NullString
+	if err := row.Scan(&d.Org, &model, &reasoning, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if model.Valid {
+		d.Model = &model.String
+	}
+	if reasoning.Valid {
+		d.Reasoning = &reasoning.String
+	}
+	return &d, nil
+}
+
+// quotaError is returned when creating a goal would take org past its cap
+// on active goals.
+type quotaError struct {
+	Org   string
+	Limit int
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("org %s has reached its limit of %d active goals", e.Org, e.Limit)
+}
+
+// maxActivePerOrg caps how many non-terminal goals an org may have, unless
+// org_quotas overrides it for that org. Zero means no limit.
+var maxActivePerOrg = 0
+
+// orgQuota returns the active goal cap for org: its org_quotas override if
+// set, otherwise maxActivePerOrg.
+func orgQuota(ctx context.Context, db queryer, org string) (int, error) {
+	var limit int
+	err := db.QueryRowContext(ctx, `SELECT max_active FROM org_quotas WHERE org = ?`, org).Scan(&limit)
+	if err == sql.ErrNoRows {
+		return maxActivePerOrg, nil
+	}
+	return limit, err
+}
+
+// setOrgQuota overrides org's active goal cap; nil removes the override.
+func setOrgQuota(ctx context.Context, db *sql.DB, org string, limit *int) error {
+	if limit == nil {
+		_, err := db.ExecContext(ctx, `DELETE FROM org_quotas WHERE org = ?`, org)
+		return err
+	}
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO org_quotas (org, max_active, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(org) DO UPDATE SET max_active = excluded.max_active, updated_at = excluded.updated_at`,
+		org, *limit, timestamp(time.Now()),
+	)
+	return err
+}
+
+// countActiveGoals counts org's goals that are not yet done or cancelled.
+func countActiveGoals(ctx context.Context, db queryer, org string) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM goals WHERE org = ? AND status NOT IN ('done','cancelled')`, org,
+	).Scan(&n)
+	return n, err
+}
+
+// setOrgDefaults replaces org's defaults; nil clears a field.
+func setOrgDefaults(ctx context.Context, db *sql.DB, org string, model, reasoning *string) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO org_defaults (org, model, reasoning, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(org) DO UPDATE SET model = excluded.model, reasoning = excluded.reasoning, updated_at = excluded.updated_at`,
+		org, model, reasoning, timestamp(time.Now()),
+	)
+	return err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDBPool(t *testing.T) {
+	// holdConn keeps one connection busy with an open read, then reports
+	// whether a second query gets through while it is held
+	holdConn := func(t *testing.T, db *sql.DB) error {
+		t.Helper()
+		ctx := context.Background()
+		if _, err := createGoal(ctx, db, "org", "repo", "Goal", "Body", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		rows, err := tx.QueryContext(ctx, `SELECT id FROM goals`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		qctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		var n int
+		return db.QueryRowContext(qctx, `SELECT COUNT(*) FROM goals`).Scan(&n)
+	}
+
+	t.Run("single connection by default", func(t *testing.T) {
+		db, err := openDB(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if err := holdConn(t, db); err != context.DeadlineExceeded {
+			t.Fatalf("expected the second read to wait for the connection, got %v", err)
+		}
+	})
+
+	t.Run("concurrent reads with a larger pool", func(t *testing.T) {
+		dbMaxOpenConns = 4
+		defer func() { dbMaxOpenConns = 1 }()
+		db, err := openDB(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if err := holdConn(t, db); err != nil {
+			t.Fatalf("expected the second read to proceed, got %v", err)
+		}
+
+		// Every connection gets the pragmas, not just the first
+		ctx := context.Background()
+		conns := make([]*sql.Conn, 3)
+		for i := range conns {
+			if conns[i], err = db.Conn(ctx); err != nil {
+				t.Fatal(err)
+			}
+			defer conns[i].Close()
+		}
+		for i, c := range conns {
+			var fk, timeout int
+			if err := c.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&fk); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.QueryRowContext(ctx, `PRAGMA busy_timeout`).Scan(&timeout); err != nil {
+				t.Fatal(err)
+			}
+			if fk != 1 || timeout != 5000 {
+				t.Fatalf("connection %d: expected foreign_keys=1 busy_timeout=5000, got %d %d", i, fk, timeout)
+			}
+		}
+	})
+}
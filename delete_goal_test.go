@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestDeleteGoal(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	deleteReq := func(id int64, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("DELETE", "/goals/"+strconv.FormatInt(id, 10)+query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	countRows := func(query string, args ...any) int {
+		var n int
+		if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	t.Run("missing goal returns 404", func(t *testing.T) {
+		w := deleteReq(9999, "")
+		if w.Code != 404 {
+			t.Fatalf("expected 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("goal with dependents returns 409 without force", func(t *testing.T) {
+		idA, err := createGoal(context.Background(), db, "org", "repo", "Goal A", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		idB, err := createGoal(context.Background(), db, "org", "repo", "Goal B", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := addDependency(context.Background(), db, idB, idA); err != nil {
+			t.Fatal(err)
+		}
+
+		w := deleteReq(idA, "")
+		if w.Code != 409 {
+			t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+		}
+		if _, err := getGoal(context.Background(), db, idA); err != nil {
+			t.Fatalf("expected goal A to still exist, got %v", err)
+		}
+	})
+
+	t.Run("forced delete leaves no dangling rows", func(t *testing.T) {
+		idA, err := createGoal(context.Background(), db, "org", "repo", "Goal A", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		idB, err := createGoal(context.Background(), db, "org", "repo", "Goal B", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		idC, err := createGoal(context.Background(), db, "org", "repo", "Goal C", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// B depends on A, and A depends on C, so A appears on both sides
+		if err := addDependency(context.Background(), db, idB, idA); err != nil {
+			t.Fatal(err)
+		}
+		if err := addDependency(context.Background(), db, idA, idC); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := createComment(context.Background(), db, idA, "a comment"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := createAttachment(context.Background(), db, idA, "notes.md", "content"); err != nil {
+			t.Fatal(err)
+		}
+		if err := updateGoalStatus(context.Background(), db, idA, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+
+		w := deleteReq(idA, "?force=true")
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		if _, err := getGoal(context.Background(), db, idA); err != sql.ErrNoRows {
+			t.Fatalf("expected goal to be gone, got %v", err)
+		}
+		if n := countRows(`SELECT COUNT(*) FROM goal_comments WHERE goal_id = ?`, idA); n != 0 {
+			t.Fatalf("expected 0 comments, got %d", n)
+		}
+		if n := countRows(`SELECT COUNT(*) FROM goal_transitions WHERE goal_id = ?`, idA); n != 0 {
+			t.Fatalf("expected 0 transitions, got %d", n)
+		}
+		if n := countRows(`SELECT COUNT(*) FROM goal_attachments WHERE goal_id = ?`, idA); n != 0 {
+			t.Fatalf("expected 0 attachments, got %d", n)
+		}
+		if n := countRows(`SELECT COUNT(*) FROM goal_dependencies WHERE goal_id = ? OR depends_on_id = ?`, idA, idA); n != 0 {
+			t.Fatalf("expected 0 dependency rows, got %d", n)
+		}
+
+		// Unrelated goals survive
+		for _, id := range []int64{idB, idC} {
+			if _, err := getGoal(context.Background(), db, id); err != nil {
+				t.Fatalf("expected goal %d to survive, got %v", id, err)
+			}
+		}
+	})
+}
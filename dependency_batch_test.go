@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestDependencyBatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	newGoal := func(title string) int64 {
+		id, err := createGoal(context.Background(), db, "org", "repo", title, "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	addDeps := func(id int64, dependsOn ...int64) (*httptest.ResponseRecorder, map[string]any) {
+		body, _ := json.Marshal(map[string]any{"depends_on_ids": dependsOn})
+		req := httptest.NewRequest("POST", "/goals/"+strconv.FormatInt(id, 10)+"/dependencies", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w, resp
+	}
+
+	t.Run("three at once", func(t *testing.T) {
+		g, a, b, c := newGoal("G"), newGoal("A"), newGoal("B"), newGoal("C")
+		if w, resp := addDeps(g, a, b, c); w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %v", w.Code, resp)
+		}
+		deps, err := listDependencies(context.Background(), db, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deps) != 3 || deps[0] != a || deps[1] != b || deps[2] != c {
+			t.Fatalf("expected [%d %d %d], got %v", a, b, c, deps)
+		}
+	})
+
+	t.Run("cycle rolls back batch", func(t *testing.T) {
+		a, b, c := newGoal("A"), newGoal("B"), newGoal("C")
+		if w, resp := addDeps(b, a); w.Code != 201 {
+			t.Fatalf("B->A: expected 201, got %d: %v", w.Code, resp)
+		}
+		// A->C is fine on its own, A->B closes A->B->A
+		w, resp := addDeps(a, c, b)
+		if w.Code != 409 {
+			t.Fatalf("expected 409, got %d: %v", w.Code, resp)
+		}
+		if resp["depends_on_id"] != float64(b) {
+			t.Fatalf("expected offending id %d, got %v", b, resp["depends_on_id"])
+		}
+		deps, err := listDependencies(context.Background(), db, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deps) != 0 {
+			t.Fatalf("expected no dependencies added, got %v", deps)
+		}
+	})
+
+	t.Run("missing goal rolls back batch", func(t *testing.T) {
+		g, a := newGoal("G"), newGoal("A")
+		w, resp := addDeps(g, a, 9999)
+		if w.Code != 404 {
+			t.Fatalf("expected 404, got %d: %v", w.Code, resp)
+		}
+		if resp["depends_on_id"] != float64(9999) {
+			t.Fatalf("expected offending id 9999, got %v", resp["depends_on_id"])
+		}
+		deps, err := listDependencies(context.Background(), db, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deps) != 0 {
+			t.Fatalf("expected no dependencies added, got %v", deps)
+		}
+	})
+
+	t.Run("self and empty rejected", func(t *testing.T) {
+		g, a := newGoal("G"), newGoal("A")
+		if w, _ := addDeps(g, a, g); w.Code != 400 {
+			t.Fatalf("expected 400 for self, got %d", w.Code)
+		}
+		if w, _ := addDeps(g); w.Code != 400 {
+			t.Fatalf("expected 400 for empty batch, got %d", w.Code)
+		}
+	})
+}
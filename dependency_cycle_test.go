@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestDependencyCycles(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	newGoal := func(title string) int64 {
+		id, err := createGoal(context.Background(), db, "org", "repo", title, "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	addDep := func(id, dependsOn int64) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{"depends_on_id": dependsOn})
+		req := httptest.NewRequest("POST", "/goals/"+strconv.FormatInt(id, 10)+"/dependencies", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("three node cycle rejected", func(t *testing.T) {
+		a, b, c := newGoal("A"), newGoal("B"), newGoal("C")
+
+		if w := addDep(a, b); w.Code != 201 {
+			t.Fatalf("A->B: expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := addDep(b, c); w.Code != 201 {
+			t.Fatalf("B->C: expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+
+		w := addDep(c, a)
+		if w.Code != 409 {
+			t.Fatalf("C->A: expected 409, got %d: %s", w.Code, w.Body.String())
+		}
+
+		deps, err := listDependencies(context.Background(), db, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deps) != 0 {
+			t.Fatalf("expected C to have no dependencies, got %v", deps)
+		}
+	})
+
+	t.Run("re-adding an edge is a no-op", func(t *testing.T) {
+		a, b := newGoal("A"), newGoal("B")
+		for range 2 {
+			if w := addDep(a, b); w.Code != 201 {
+				t.Fatalf("A->B: expected 201, got %d: %s", w.Code, w.Body.String())
+			}
+		}
+		deps, err := listDependencies(context.Background(), db, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deps) != 1 || deps[0] != b {
+			t.Fatalf("expected A to depend on B once, got %v", deps)
+		}
+	})
+
+	t.Run("diamond allowed", func(t *testing.T) {
+		a, b, c, d := newGoal("A"), newGoal("B"), newGoal("C"), newGoal("D")
+
+		for _, edge := range [][2]int64{{a, b}, {a, c}, {b, d}, {c, d}} {
+			if w := addDep(edge[0], edge[1]); w.Code != 201 {
+				t.Fatalf("%d->%d: expected 201, got %d: %s", edge[0], edge[1], w.Code, w.Body.String())
+			}
+		}
+	})
+}
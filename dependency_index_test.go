@@ -0,0 +1,41 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDependentsQueryUsesIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`EXPLAIN QUERY PLAN SELECT goal_id FROM goal_dependencies WHERE depends_on_id = ? ORDER BY goal_id`, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			t.Fatal(err)
+		}
+		plan = append(plan, detail)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	joined := strings.Join(plan, "\n")
+	if !strings.Contains(joined, "idx_deps_depends_on") {
+		t.Fatalf("expected plan to use idx_deps_depends_on, got:\n%s", joined)
+	}
+}
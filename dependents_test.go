@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestListDependents(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	var ids []int64
+	for _, title := range []string{"A", "B", "C"} {
+		id, err := createGoal(context.Background(), db, "org", "repo", title, "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	idA, idB, idC := ids[0], ids[1], ids[2]
+
+	// B and C both depend on A
+	if err := addDependency(context.Background(), db, idB, idA); err != nil {
+		t.Fatal(err)
+	}
+	if err := addDependency(context.Background(), db, idC, idA); err != nil {
+		t.Fatal(err)
+	}
+
+	getDependents := func(id int64) []any {
+		req := httptest.NewRequest("GET", "/goals/"+strconv.FormatInt(id, 10)+"/dependents", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp["items"].([]any)
+	}
+
+	t.Run("lists goals depending on A", func(t *testing.T) {
+		items := getDependents(idA)
+		if len(items) != 2 {
+			t.Fatalf("expected 2 dependents, got %v", items)
+		}
+		if int64(items[0].(float64)) != idB || int64(items[1].(float64)) != idC {
+			t.Fatalf("expected [%d %d], got %v", idB, idC, items)
+		}
+	})
+
+	t.Run("no dependents returns empty array", func(t *testing.T) {
+		items := getDependents(idB)
+		if len(items) != 0 {
+			t.Fatalf("expected no dependents, got %v", items)
+		}
+	})
+}
+
+func TestListLinkedGoalsPagedAndExpanded(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	ctx := context.Background()
+	var ids []int64
+	for _, title := range []string{"A", "B", "C", "D"} {
+		id, err := createGoal(ctx, db, "org", "repo", title, "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	idA, idB := ids[0], ids[1]
+	// A depends on B, C and D; B is queued
+	for _, dep := range ids[1:] {
+		if err := addDependency(ctx, db, idA, dep); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := updateGoalStatus(ctx, db, idB, "draft", "queued"); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) (int, map[string]any) {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+	deps := "/goals/" + strconv.FormatInt(idA, 10) + "/dependencies"
+
+	t.Run("expand includes statuses", func(t *testing.T) {
+		_, resp := get(deps + "?expand=status")
+		items := resp["items"].([]any)
+		if len(items) != 3 {
+			t.Fatalf("expected 3 dependencies, got %v", items)
+		}
+		first := items[0].(map[string]any)
+		if int64(first["id"].(float64)) != idB || first["status"] != "queued" {
+			t.Fatalf("expected B as queued, got %v", first)
+		}
+		if items[1].(map[string]any)["status"] != "draft" {
+			t.Fatalf("expected C as draft, got %v", items[1])
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		_, resp := get(deps + "?page=1&per_page=2")
+		if items := resp["items"].([]any); len(items) != 2 || int64(items[0].(float64)) != idB {
+			t.Fatalf("expected B and C on page 1, got %v", items)
+		}
+		if resp["total"] != float64(3) {
+			t.Fatalf("expected total 3, got %v", resp["total"])
+		}
+		_, resp = get(deps + "?page=2&per_page=2")
+		if items := resp["items"].([]any); len(items) != 1 || int64(items[0].(float64)) != ids[3] {
+			t.Fatalf("expected D on page 2, got %v", items)
+		}
+	})
+
+	t.Run("dependents expanded", func(t *testing.T) {
+		_, resp := get("/goals/" + strconv.FormatInt(idB, 10) + "/dependents?expand=status")
+		items := resp["items"].([]any)
+		if len(items) != 1 || items[0].(map[string]any)["status"] != "draft" {
+			t.Fatalf("expected A as draft, got %v", items)
+		}
+	})
+
+	t.Run("invalid expand", func(t *testing.T) {
+		if code, _ := get(deps + "?expand=title"); code != 400 {
+			t.Fatalf("expected 400, got %d", code)
+		}
+	})
+}
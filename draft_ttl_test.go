@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStaleDraftSweep(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	newGoal := func() int64 {
+		id, err := createGoal(ctx, db, "org", "repo", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	stale := newGoal()
+	fresh := newGoal()
+	oldQueued := newGoal()
+	if err := updateGoalStatus(ctx, db, oldQueued, "draft", "queued"); err != nil {
+		t.Fatal(err)
+	}
+	old := timestamp(time.Now().Add(-31 * 24 * time.Hour))
+	if _, err := db.Exec(`UPDATE goals SET created_at = ? WHERE id IN (?, ?)`, old, stale, oldQueued); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	startDraftSweeper(ctx, newSQLiteStore(db), 720*time.Hour, 10*time.Millisecond)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		g, err := getGoal(ctx, db, stale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Status == "cancelled" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected stale draft to be cancelled, still %s", g.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	transitions, err := listTransitions(ctx, db, stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := transitions[len(transitions)-1]
+	if last.ToStatus != "cancelled" || last.Note == nil || *last.Note != staleDraftNote {
+		t.Fatalf("expected cancellation noted %q, got %+v", staleDraftNote, last)
+	}
+
+	for id, want := range map[int64]string{fresh: "draft", oldQueued: "queued"} {
+		g, err := getGoal(ctx, db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Status != want {
+			t.Fatalf("goal %d: expected %s to be left alone, got %s", id, want, g.Status)
+		}
+	}
+}
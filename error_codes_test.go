@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestErrorCodes(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+	handler := withRouteErrors(mux)
+
+	ctx := context.Background()
+	a, err := createGoal(ctx, db, "org", "repo", "A", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := createGoal(ctx, db, "org", "repo", "B", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addDependency(ctx, db, b, a); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int64{a, b} {
+		if err := updateGoalStatus(ctx, db, id, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name, method, path, body string
+		status                   int
+		code                     string
+	}{
+		{"missing goal", "GET", "/goals/999", "", 404, codeGoalNotFound},
+		{"unknown route", "GET", "/nope", "", 404, codeNotFound},
+		{"invalid transition", "PATCH", "/goals/" + strconv.FormatInt(a, 10) + "/done", "", 409, codeInvalidTransition},
+		{"unmet dependencies", "PATCH", "/goals/" + strconv.FormatInt(b, 10) + "/start", "", 409, codeUnmetDependencies},
+		{"dependency cycle", "POST", "/goals/" + strconv.FormatInt(a, 10) + "/dependencies", `{"depends_on_id": ` + strconv.FormatInt(b, 10) + `}`, 409, codeDependencyCycle},
+		{"validation", "POST", "/goals", `{"org": "org", "repo": "repo", "title": "T"}`, 400, codeValidation},
+		{"invalid JSON", "POST", "/goals", `{`, 400, codeValidation},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tc.status {
+				t.Fatalf("expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			var resp struct {
+				OK    bool   `json:"ok"`
+				Code  string `json:"code"`
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.OK || resp.Code != tc.code || resp.Error == "" {
+				t.Fatalf("expected code %q with a message, got %s", tc.code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestIfMatchTransitions(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	id, err := createGoal(context.Background(), db, "org", "repo", "Contended Goal", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goalURL := "/goals/" + strconv.FormatInt(id, 10)
+
+	getETag := func() string {
+		req := httptest.NewRequest("GET", goalURL, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		etag := w.Header().Get("ETag")
+		if etag == "" {
+			t.Fatal("expected ETag header on GET")
+		}
+		return etag
+	}
+
+	patch := func(action, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", goalURL+"/"+action, nil)
+		req.Header.Set("If-Match", etag)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("stale ETag returns 412", func(t *testing.T) {
+		stale := getETag()
+		// Another writer touches the goal after our read
+		if _, err := db.Exec(`UPDATE goals SET version = version + 1 WHERE id = ?`, id); err != nil {
+			t.Fatal(err)
+		}
+
+		w := patch("queue", stale)
+		if w.Code != 412 {
+			t.Fatalf("expected 412, got %d: %s", w.Code, w.Body.String())
+		}
+		g, err := getGoal(context.Background(), db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Status != "draft" {
+			t.Fatalf("expected status to remain draft, got %s", g.Status)
+		}
+	})
+
+	t.Run("matching ETag transitions", func(t *testing.T) {
+		w := patch("queue", getETag())
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		g, err := getGoal(context.Background(), db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Status != "queued" {
+			t.Fatalf("expected status=queued, got %s", g.Status)
+		}
+	})
+
+	t.Run("ETag changes on every write", func(t *testing.T) {
+		before := getETag()
+		req := httptest.NewRequest("PATCH", goalURL+"/priority", strings.NewReader(`{"priority": 5}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if after := getETag(); after == before {
+			t.Fatalf("expected ETag to change, still %s", after)
+		}
+	})
+
+	t.Run("stale version is rejected by the update", func(t *testing.T) {
+		g, err := getGoal(context.Background(), db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A write lands between the If-Match check and the update
+		if err := updateGoalPriority(context.Background(), db, id, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		err = transitionGoal(context.Background(), db, id, "queued", "running", g.Version, nil)
+		if err != errGoalModified {
+			t.Fatalf("expected errGoalModified, got %v", err)
+		}
+		g, err = getGoal(context.Background(), db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Status != "queued" {
+			t.Fatalf("expected status to remain queued, got %s", g.Status)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// exportBatchSize is how many goals are read per query while exporting, which
+// bounds memory use regardless of database size.
+const exportBatchSize = 100
+
+// GoalExport is one line of an export: a goal with everything attached to it.
+type GoalExport struct {
+	Goal         *Goal        `json:"goal"`
+	Comments     []Comment    `json:"comments"`
+	Transitions  []Transition `json:"transitions"`
+	Dependencies []int64      `json:"dependencies"`
+	Tags         []string     `json:"tags"`
+	Attachments  []Attachment `json:"attachments"`
+}
+
+// exportGoal gathers a goal and its comments, transitions, dependencies,
+// tags and attachments.
+func exportGoal(ctx context.Context, store Store, id int64) (*GoalExport, error) {
+	g, err := store.GetGoal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	e := &GoalExport{Goal: g}
+	if e.Comments, err = store.ListComments(ctx, id); err != nil {
+		return nil, err
+	}
+	if e.Transitions, err = store.ListTransitions(ctx, id); err != nil {
+		return nil, err
+	}
+	if e.Dependencies, err = store.ListDependencies(ctx, id); err != nil {
+		return nil, err
+	}
+	if e.Tags, err = store.ListTags(ctx, id); err != nil {
+		return nil, err
+	}
+	if e.Attachments, err = store.ListAttachmentBodies(ctx, id); err != nil {
+		return nil, err
+	}
+	// Empty lists are written as [] rather than null
+	if e.Comments == nil {
+		e.Comments = []Comment{}
+	}
+	if e.Dependencies == nil {
+		e.Dependencies = []int64{}
+	}
+	if e.Tags == nil {
+		e.Tags = []string{}
+	}
+	if e.Attachments == nil {
+		e.Attachments = []Attachment{}
+	}
+	return e, nil
+}
+
+// handleExport streams every goal as NDJSON, one GoalExport per line in id
+// order. Goals are read in batches rather than in one transaction, so a goal
+// changed mid-export is written as of when its batch was read.
+func handleExport(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ew := &deferredWriter{w: w, header: func() {
+			name := "plans-" + time.Now().UTC().Format("2006-01-02T15-04-05") + ".ndjson"
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		}}
+		if err := writeExport(r.Context(), store, ew, http.NewResponseController(w)); err != nil {
+			log.Printf("request %s: export failed: %v", requestID(r.Context()), err)
+			if !ew.started {
+				writeErr(w, 500, "failed to export goals")
+			}
+		}
+	}
+}
+
+func writeExport(ctx context.Context, store Store, w *deferredWriter, rc *http.ResponseController) error {
+	enc := json.NewEncoder(w)
+	var after int64
+	for {
+		ids, err := store.GoalIDsAfter(ctx, after, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			if !w.started {
+				// An empty database still gets the export headers
+				w.Write(nil)
+			}
+			return nil
+		}
+		for _, id := range ids {
+			e, err := exportGoal(ctx, store, id)
+			if err == sql.ErrNoRows {
+				continue // deleted since its id was read
+			}
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return err
+		}
+		after = ids[len(ids)-1]
+	}
+}
+
+// handleGoalsCSV streams the goals matching the GET /goals filters as CSV in
+// id order, for spreadsheets.
+func handleGoalsCSV(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, ok := goalFilterFromRequest(w, r)
+		if !ok {
+			return
+		}
+		if filter.Sort != "" || filter.Order != "" {
+			writeErr(w, 400, "sort and order are not supported for CSV")
+			return
+		}
+		cw := &deferredWriter{w: w, header: func() {
+			name := "goals-" + time.Now().UTC().Format("2006-01-02T15-04-05") + ".csv"
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		}}
+		if err := writeGoalsCSV(r.Context(), store, filter, cw, http.NewResponseController(w)); err != nil {
+			log.Printf("request %s: CSV export failed: %v", requestID(r.Context()), err)
+			if !cw.started {
+				writeErr(w, 500, "failed to export goals")
+			}
+		}
+	}
+}
+
+func writeGoalsCSV(ctx context.Context, store Store, filter GoalFilter, w *deferredWriter, rc *http.ResponseController) error {
+	// The first batch is read before anything is written, so a failing query
+	// can still become a 500
+	goals, err := store.GoalsAfter(ctx, filter, 0, exportBatchSize)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "org", "repo", "title", "status", "model", "reasoning", "created_at", "updated_at"})
+	for len(goals) > 0 {
+		for _, g := range goals {
+			cw.Write([]string{
+				strconv.FormatInt(g.ID, 10), g.Org, g.Repo, g.Title, g.Status,
+				*cmp.Or(g.Model, new(string)), *cmp.Or(g.Reasoning, new(string)),
+				g.CreatedAt, g.UpdatedAt,
+			})
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+		if err := rc.Flush(); err != nil {
+			return err
+		}
+		if goals, err = store.GoalsAfter(ctx, filter, goals[len(goals)-1].ID, exportBatchSize); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// maxImportBytes caps the size of an import body, which is decoded in full
+// before anything is written.
+var maxImportBytes int64 = 64 << 20
+
+// handleImport loads an export (NDJSON, one GoalExport per line) in a single
+// transaction, so either every goal is imported or none is. Goals keep their
+// ids unless preserve_ids=false; mode (error, skip or replace) decides what
+// happens when an id is already taken.
+func handleImport(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+		q := r.URL.Query()
+		mode := cmp.Or(q.Get("mode"), importError)
+		if mode != importError && mode != importSkip && mode != importReplace {
+			writeErr(w, 400, "mode must be error, skip, or replace")
+			return
+		}
+		preserveIDs := true
+		if v := q.Get("preserve_ids"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				writeErr(w, 400, "preserve_ids must be true or false")
+				return
+			}
+			preserveIDs = b
+		}
+
+		var goals []GoalExport
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		for line := 1; ; line++ {
+			var e GoalExport
+			err := dec.Decode(&e)
+			if err == io.EOF {
+				break
+			}
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeErr(w, 413, fmt.Sprintf("import exceeds %d bytes", tooLarge.Limit))
+				return
+			}
+			if err != nil {
+				writeErr(w, 400, fmt.Sprintf("line %d: invalid JSON: %v", line, err))
+				return
+			}
+			goals = append(goals, e)
+		}
+
+		result, err := store.ImportGoals(r.Context(), goals, preserveIDs, mode)
+		var conflict *importConflictError
+		var invalid *invalidImportError
+		switch {
+		case errors.As(err, &conflict):
+			writeErr(w, 409, err.Error())
+			return
+		case errors.As(err, &invalid):
+			writeErr(w, 400, err.Error())
+			return
+		case err != nil:
+			log.Printf("request %s: import failed: %v", requestID(r.Context()), err)
+			writeErr(w, 500, "failed to import goals")
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "imported": result.Imported, "skipped": result.Skipped})
+	}
+}
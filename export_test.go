@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	adminAPIKey = "secret"
+	defer func() { adminAPIKey = "" }()
+
+	ctx := context.Background()
+	model := "opus"
+	a, err := createGoal(ctx, db, "org", "repo", "A", "Body A", &model, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := createGoal(ctx, db, "org", "repo", "B, with \"quotes\"\nand lines", "Body B", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addDependency(ctx, db, b, a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createComment(ctx, db, a, "a comment"); err != nil {
+		t.Fatal(err)
+	}
+	if err := addTag(ctx, db, a, "infra"); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateGoalStatus(ctx, db, a, "draft", "queued"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/admin/export", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("expected NDJSON, got %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") {
+		t.Fatalf("expected attachment disposition, got %q", cd)
+	}
+
+	var exports []GoalExport
+	sc := bufio.NewScanner(bytes.NewReader(w.Body.Bytes()))
+	for sc.Scan() {
+		line := sc.Bytes()
+		var e GoalExport
+		if err := json.Unmarshal(line, &e); err != nil {
+			t.Fatalf("line is not a goal export: %v: %s", err, line)
+		}
+		// Decoding and re-encoding must give back the same line
+		again, _ := json.Marshal(e)
+		if !bytes.Equal(again, line) {
+			t.Fatalf("export does not round-trip:\n%s\n%s", line, again)
+		}
+		exports = append(exports, e)
+	}
+	if len(exports) != 2 {
+		t.Fatalf("expected 2 goals, got %d", len(exports))
+	}
+
+	ea, eb := exports[0], exports[1]
+	if ea.Goal.ID != a || ea.Goal.Status != "queued" || ea.Goal.Model == nil || *ea.Goal.Model != "opus" {
+		t.Fatalf("unexpected goal A: %+v", ea.Goal)
+	}
+	if len(ea.Comments) != 1 || ea.Comments[0].Body != "a comment" {
+		t.Fatalf("expected A's comment, got %+v", ea.Comments)
+	}
+	if len(ea.Transitions) != 2 || ea.Transitions[1].ToStatus != "queued" {
+		t.Fatalf("expected A's 2 transitions, got %+v", ea.Transitions)
+	}
+	if len(ea.Tags) != 1 || ea.Tags[0] != "infra" || len(ea.Dependencies) != 0 {
+		t.Fatalf("unexpected A tags/deps: %v %v", ea.Tags, ea.Dependencies)
+	}
+	if eb.Goal.Title != "B, with \"quotes\"\nand lines" {
+		t.Fatalf("unexpected B title %q", eb.Goal.Title)
+	}
+	if len(eb.Dependencies) != 1 || eb.Dependencies[0] != a || eb.Comments == nil {
+		t.Fatalf("unexpected B: deps %v comments %v", eb.Dependencies, eb.Comments)
+	}
+
+	t.Run("flushes through the middleware", func(t *testing.T) {
+		f, err := os.Create(filepath.Join(tmpDir, "access.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		lg := &requestLogger{f: f}
+		for _, gz := range []bool{false, true} {
+			req := httptest.NewRequest("GET", "/admin/export", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			if gz {
+				req.Header.Set("Accept-Encoding", "gzip")
+			}
+			w := httptest.NewRecorder()
+			lg.wrap(withGzip(mux)).ServeHTTP(w, req)
+			if w.Code != 200 {
+				t.Fatalf("expected 200 (gzip %v), got %d: %s", gz, w.Code, w.Body.String())
+			}
+			if !w.Flushed {
+				t.Fatalf("expected the export to be flushed (gzip %v)", gz)
+			}
+		}
+	})
+
+	t.Run("requires admin", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/admin/export", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 401 {
+			t.Fatalf("expected 401, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestGoalOrder(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*http.ServeMux, func(title string) int64, func(goalID, dependsOnID int64)) {
+		dbPath := filepath.Join(t.TempDir(), "test.db")
+		db, err := openDB(dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		mux := http.NewServeMux()
+		registerRoutes(mux, newSQLiteStore(db))
+
+		queued := func(title string) int64 {
+			id, err := createGoal(ctx, db, "org", "repo", title, "B", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := updateGoalStatus(ctx, db, id, "draft", "queued"); err != nil {
+				t.Fatal(err)
+			}
+			return id
+		}
+		// Edges are inserted directly, since the API refuses to close a cycle
+		dep := func(goalID, dependsOnID int64) {
+			if err := addDependency(ctx, db, goalID, dependsOnID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return mux, queued, dep
+	}
+
+	get := func(mux *http.ServeMux, query string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest("GET", "/goals/order"+query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w, resp
+	}
+
+	t.Run("chain", func(t *testing.T) {
+		mux, queued, dep := setup(t)
+		// Created in reverse so id order alone would be wrong
+		c := queued("C")
+		b := queued("B")
+		a := queued("A")
+		dep(b, a)
+		dep(c, b)
+
+		w, resp := get(mux, "?status=queued")
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %v", w.Code, resp)
+		}
+		var titles []string
+		for _, item := range resp["items"].([]any) {
+			titles = append(titles, item.(map[string]any)["title"].(string))
+		}
+		if len(titles) != 3 || titles[0] != "A" || titles[1] != "B" || titles[2] != "C" {
+			t.Fatalf("expected [A B C], got %v", titles)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		mux, queued, dep := setup(t)
+		a := queued("A")
+		b := queued("B")
+		dep(a, b)
+		dep(b, a)
+
+		w, resp := get(mux, "")
+		if w.Code != 409 {
+			t.Fatalf("expected 409, got %d: %v", w.Code, resp)
+		}
+		cycle, _ := resp["cycle"].([]any)
+		if len(cycle) != 3 || cycle[0] != cycle[2] {
+			t.Fatalf("expected cycle closing on its first goal, got %v", resp["cycle"])
+		}
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		mux, _, _ := setup(t)
+		if w, _ := get(mux, "?status=merged"); w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGoalsCSV(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	ctx := context.Background()
+	model := "opus"
+	a, err := createGoal(ctx, db, "org", "repo", "Fix parser, lexer", "Body", &model, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createGoal(ctx, db, "org", "other", "Other repo", "Body", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/goals.csv"+query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("?repo=repo")
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("expected text/csv, got %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, ".csv") {
+		t.Fatalf("expected a .csv filename, got %q", cd)
+	}
+
+	lines := strings.Split(w.Body.String(), "\n")
+	if lines[0] != "id,org,repo,title,status,model,reasoning,created_at,updated_at" {
+		t.Fatalf("unexpected header row %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `,"Fix parser, lexer",`) {
+		t.Fatalf("expected title with a comma to be quoted, got %q", lines[1])
+	}
+
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected header and 1 goal, got %v", records)
+	}
+	g, _ := getGoal(ctx, db, a)
+	want := []string{"1", "org", "repo", "Fix parser, lexer", "draft", "opus", "", g.CreatedAt, g.UpdatedAt}
+	if !slices.Equal(records[1], want) {
+		t.Fatalf("expected %v, got %v", want, records[1])
+	}
+
+	t.Run("invalid filter", func(t *testing.T) {
+		if w := get("?model=gpt"); w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// gzipMinSize is the smallest response worth compressing; below it the
+// gzip framing costs more than it saves.
+const gzipMinSize = 1024
+
+// withGzip compresses responses for clients that accept gzip. Small bodies
+// and event streams are sent as-is.
+func withGzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		gw := &gzipWriter{ResponseWriter: w, status: 200}
+		defer gw.Close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if name == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipWriter holds back the status and the first gzipMinSize bytes until it
+// knows whether the response is worth compressing, then commits either way.
+// The status is forwarded to the wrapped writer, so an outer statusWriter
+// still records it.
+type gzipWriter struct {
+	http.ResponseWriter
+	status    int
+	buf       bytes.Buffer
+	gz        *gzip.Writer
+	committed bool
+}
+
+func (g *gzipWriter) WriteHeader(code int) {
+	if !g.committed {
+		g.status = code
+	}
+}
+
+func (g *gzipWriter) Write(p []byte) (int, error) {
+	if g.committed {
+		if g.gz != nil {
+			return g.gz.Write(p)
+		}
+		return g.ResponseWriter.Write(p)
+	}
+	if g.isStream() {
+		g.commit(false)
+		return g.ResponseWriter.Write(p)
+	}
+	g.buf.Write(p)
+	if g.buf.Len() >= gzipMinSize {
+		if err := g.commit(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends buffered data immediately, giving up on compression if the
+// response is not yet committed.
+func (g *gzipWriter) Flush() {
+	if !g.committed {
+		g.commit(false)
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (g *gzipWriter) isStream() bool {
+	return strings.HasPrefix(g.Header().Get("Content-Type"), "text/event-stream")
+}
+
+func (g *gzipWriter) commit(compress bool) error {
+	g.committed = true
+	if compress && g.Header().Get("Content-Encoding") == "" {
+		g.Header().Set("Content-Encoding", "gzip")
+		g.Header().Del("Content-Length")
+		g.ResponseWriter.WriteHeader(g.status)
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+		_, err := g.gz.Write(g.buf.Bytes())
+		g.buf.Reset()
+		return err
+	}
+	g.ResponseWriter.WriteHeader(g.status)
+	_, err := g.ResponseWriter.Write(g.buf.Bytes())
+	g.buf.Reset()
+	return err
+}
+
+func (g *gzipWriter) Close() error {
+	if !g.committed {
+		return g.commit(false)
+	}
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGzipResponses(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 20; i++ {
+		if _, err := createGoal(context.Background(), db, "org", "repo", "Goal", strings.Repeat("long body ", 50), nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+	h := withGzip(mux)
+
+	get := func(path string, gz bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if gz {
+			req.Header.Set("Accept-Encoding", "gzip, deflate")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("list decompresses to the plain response", func(t *testing.T) {
+		plain := get("/goals", false)
+		if plain.Header().Get("Content-Encoding") != "" {
+			t.Fatal("expected no encoding without Accept-Encoding")
+		}
+
+		w := get("/goals", true)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if w.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("expected gzip encoding, got %q", w.Header().Get("Content-Encoding"))
+		}
+		zr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(body, plain.Body.Bytes()) {
+			t.Fatal("decompressed body differs from uncompressed response")
+		}
+		var resp map[string]any
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp["items"].([]any)) != 20 {
+			t.Fatalf("expected 20 items, got %d", len(resp["items"].([]any)))
+		}
+	})
+
+	t.Run("small responses are not compressed", func(t *testing.T) {
+		w := get("/goals/1/tags", true)
+		if w.Header().Get("Content-Encoding") != "" {
+			t.Fatal("expected small response to be sent uncompressed")
+		}
+		var resp map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("logged status survives the wrapper", func(t *testing.T) {
+		logPath := filepath.Join(tmpDir, "access.jsonl")
+		f, err := os.Create(logPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		lg := &requestLogger{f: f}
+
+		created := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, 201, map[string]any{"ok": true, "body": strings.Repeat("x", 2*gzipMinSize)})
+		})
+		req := httptest.NewRequest("POST", "/goals", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		lg.wrap(withGzip(created)).ServeHTTP(w, req)
+		if w.Code != 201 || w.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("expected gzipped 201, got %d %q", w.Code, w.Header().Get("Content-Encoding"))
+		}
+
+		f.Seek(0, io.SeekStart)
+		sc := bufio.NewScanner(f)
+		if !sc.Scan() {
+			t.Fatal("expected a log entry")
+		}
+		var entry logEntry
+		if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.Status != 201 {
+			t.Fatalf("expected logged status 201, got %d", entry.Status)
+		}
+	})
+}
@@ -1,33 +1,140 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
 	"net/http"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
-func registerRoutes(mux *http.ServeMux, db *sql.DB) {
-	mux.HandleFunc("POST /goals", handleCreateGoal(db))
-	mux.HandleFunc("GET /goals/{id}", handleGetGoal(db))
-	mux.HandleFunc("GET /goals", handleListGoals(db))
-	mux.HandleFunc("PATCH /goals/{id}/queue", handleQueue(db))
-	mux.HandleFunc("PATCH /goals/{id}/start", handleStart(db))
-	mux.HandleFunc("PATCH /goals/{id}/done", handleDone(db))
-	mux.HandleFunc("PATCH /goals/{id}/stuck", handleStuck(db))
-	mux.HandleFunc("PATCH /goals/{id}/requeue", handleRequeue(db))
-	mux.HandleFunc("PATCH /goals/{id}/cancel", handleCancel(db))
-	mux.HandleFunc("POST /goals/{id}/comments", handleCreateComment(db))
-	mux.HandleFunc("GET /goals/{id}/comments", handleListComments(db))
-	mux.HandleFunc("POST /goals/{id}/dependencies", handleAddDependency(db))
-	mux.HandleFunc("DELETE /goals/{id}/dependencies/{dep_id}", handleRemoveDependency(db))
-	mux.HandleFunc("GET /goals/{id}/dependencies", handleListDependencies(db))
-	mux.HandleFunc("POST /goals/{id}/attachments", handleCreateAttachment(db))
-	mux.HandleFunc("GET /goals/{id}/attachments", handleListAttachments(db))
-	mux.HandleFunc("GET /goals/{id}/attachments/{att_id}", handleGetAttachment(db))
-	mux.HandleFunc("PATCH /goals/{id}/attachments/{att_id}", handleEditAttachment(db))
-	mux.HandleFunc("DELETE /goals/{id}/attachments/{att_id}", handleDeleteAttachment(db))
+// registerRoutes installs every route on mux and returns the HTTP methods
+// they use, sorted, so CORS preflights can advertise exactly those.
+func registerRoutes(mux *http.ServeMux, store Store) []string {
+	var methods []string
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.HandleFunc(pattern, h)
+		method, _, _ := strings.Cut(pattern, " ")
+		if !slices.Contains(methods, method) {
+			methods = append(methods, method)
+		}
+	}
+
+	handle("GET /healthz", handleHealthz(store))
+	handle("GET /admin/backup", requireAdmin(handleBackup(store)))
+	handle("GET /admin/export", requireAdmin(handleExport(store)))
+	handle("POST /admin/import", requireAdmin(handleImport(store)))
+	handle("POST /goals", handleCreateGoal(store))
+	handle("GET /goals/{id}", handleGetGoal(store))
+	handle("GET /goals", handleListGoals(store))
+	handle("GET /goals.csv", handleGoalsCSV(store))
+	handle("GET /goals/stats", handleGoalStats(store))
+	handle("GET /goals/stats/cycle-time", handleCycleTime(store))
+	handle("GET /goals/order", handleGoalOrder(store))
+	handle("POST /goals/next", handleClaimNext(store))
+	handle("POST /goals/requeue-stuck", handleRequeueStuck(store))
+	handle("PATCH /goals/{id}", handleUpdateGoal(store))
+	handle("DELETE /goals/{id}", handleDeleteGoal(store))
+	handle("POST /goals/{id}/clone", handleCloneGoal(store))
+	handle("PATCH /goals/{id}/priority", handleSetPriority(store))
+	handle("PATCH /goals/{id}/metadata", handleMergeMetadata(store))
+	handle("PATCH /goals/{id}/queue", handleQueue(store))
+	handle("PATCH /goals/{id}/start", handleStart(store))
+	handle("PATCH /goals/{id}/done", handleDone(store))
+	handle("PATCH /goals/{id}/stuck", handleStuck(store))
+	handle("PATCH /goals/{id}/requeue", handleRequeue(store))
+	handle("PATCH /goals/{id}/cancel", handleCancel(store))
+	handle("PATCH /goals/{id}/heartbeat", handleHeartbeat(store))
+	handle("GET /goals/{id}/transitions", handleListTransitions(store))
+	handle("GET /goals/{id}/timing", handleGoalTiming(store))
+	handle("GET /goals/{id}/activity", handleListActivity(store))
+	handle("GET /changes", handleListChanges(store))
+	handle("GET /goals/changes/longpoll", handleLongPollChanges(store))
+	handle("POST /goals/{id}/comments", handleCreateComment(store))
+	handle("GET /goals/{id}/comments", handleListComments(store))
+	handle("PATCH /goals/{id}/comments/{comment_id}", handleEditComment(store))
+	handle("DELETE /goals/{id}/comments/{comment_id}", handleDeleteComment(store))
+	handle("POST /goals/{id}/dependencies", handleAddDependency(store))
+	handle("DELETE /goals/{id}/dependencies/{dep_id}", handleRemoveDependency(store))
+	handle("GET /goals/{id}/dependencies", handleListDependencies(store))
+	handle("GET /goals/{id}/dependents", handleListDependents(store))
+	handle("GET /goals/{id}/children", handleListChildren(store))
+	handle("POST /goals/{id}/tags", handleAddTag(store))
+	handle("DELETE /goals/{id}/tags/{tag}", handleRemoveTag(store))
+	handle("GET /goals/{id}/tags", handleListTags(store))
+	handle("POST /goals/{id}/attachments", handleCreateAttachment(store))
+	handle("GET /goals/{id}/attachments", handleListAttachments(store))
+	handle("GET /goals/{id}/attachments/{att_id}", handleGetAttachment(store))
+	handle("PATCH /goals/{id}/attachments/{att_id}", handleEditAttachment(store))
+	handle("DELETE /goals/{id}/attachments/{att_id}", handleDeleteAttachment(store))
+	handle("GET /orgs/{org}/defaults", handleGetOrgDefaults(store))
+	handle("PUT /orgs/{org}/defaults", handleSetOrgDefaults(store))
+	handle("GET /orgs/{org}/quota", handleGetOrgQuota(store))
+	handle("PUT /admin/orgs/{org}/quota", requireAdmin(handleSetOrgQuota(store)))
+	if pprofEnabled {
+		for pattern, h := range pprofRoutes {
+			handle(pattern, requireAdmin(h))
+		}
+	}
+	slices.Sort(methods)
+	return methods
+}
+
+// withRouteErrors answers requests no route matches in the API's JSON error
+// format. The mux still decides between 404 and 405 (with its Allow header).
+func withRouteErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		rec := &headerRecorder{header: http.Header{}}
+		h.ServeHTTP(rec, r)
+		switch rec.status {
+		case http.StatusMethodNotAllowed:
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+			writeErr(w, 405, "method not allowed")
+		case http.StatusNotFound:
+			writeErr(w, 404, "not found")
+		default:
+			for k, v := range rec.header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(rec.status)
+		}
+	})
+}
+
+// headerRecorder captures the status and headers a handler writes,
+// discarding the body.
+type headerRecorder struct {
+	header http.Header
+	status int
+}
+
+func (h *headerRecorder) Header() http.Header { return h.header }
+
+func (h *headerRecorder) WriteHeader(code int) {
+	if h.status == 0 {
+		h.status = code
+	}
+}
+
+func (h *headerRecorder) Write(p []byte) (int, error) {
+	h.WriteHeader(200)
+	return len(p), nil
 }
 
 // --- helpers ---
@@ -38,426 +145,2009 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	json.NewEncoder(w).Encode(v)
 }
 
+// Error codes sent as "code" in every error response. Unlike the message
+// they are stable, so clients can branch on them.
+const (
+	codeValidation           = "validation_error"
+	codeUnauthorized         = "unauthorized"
+	codeForbidden            = "forbidden"
+	codeNotFound             = "not_found"
+	codeGoalNotFound         = "goal_not_found"
+	codeMethodNotAllowed     = "method_not_allowed"
+	codeConflict             = "conflict"
+	codeInvalidTransition    = "invalid_transition"
+	codeUnmetDependencies    = "unmet_dependencies"
+	codeDependencyCycle      = "dependency_cycle"
+	codePreconditionFailed   = "precondition_failed"
+	codeTooLarge             = "too_large"
+	codeUnsupportedMediaType = "unsupported_media_type"
+	codeRateLimited          = "rate_limited"
+	codeQuotaExceeded        = "quota_exceeded"
+	codeInternal             = "internal_error"
+	codeUnavailable          = "unavailable"
+)
+
+// statusErrorCodes is the code writeErr sends for each status; errors that
+// need a more specific one use writeErrCode.
+var statusErrorCodes = map[int]string{
+	400: codeValidation,
+	401: codeUnauthorized,
+	403: codeForbidden,
+	404: codeNotFound,
+	405: codeMethodNotAllowed,
+	409: codeConflict,
+	412: codePreconditionFailed,
+	413: codeTooLarge,
+	415: codeUnsupportedMediaType,
+	429: codeRateLimited,
+	500: codeInternal,
+	503: codeUnavailable,
+}
+
 func writeErr(w http.ResponseWriter, status int, msg string) {
-	writeJSON(w, status, map[string]any{"ok": false, "error": msg})
+	writeErrCode(w, status, statusErrorCodes[status], msg)
+}
+
+func writeErrCode(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, map[string]any{"ok": false, "code": code, "error": msg})
 }
 
+// errUnsupportedMediaType is returned by readJSON for a body sent with a
+// Content-Type other than application/json.
+var errUnsupportedMediaType = errors.New("Content-Type must be application/json")
+
+// readJSON decodes the request body into v, rejecting fields v does not
+// declare so that misspelled keys fail loudly instead of being dropped. A
+// non-empty body must be labelled application/json.
 func readJSON(r *http.Request, v any) error {
 	defer r.Body.Close()
-	return json.NewDecoder(r.Body).Decode(v)
+	if r.ContentLength != 0 {
+		mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mt != "application/json" {
+			return errUnsupportedMediaType
+		}
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// writeBodyErr reports a readJSON failure: 415 for the wrong Content-Type,
+// otherwise 400.
+func writeBodyErr(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnsupportedMediaType) {
+		writeErr(w, 415, err.Error())
+		return
+	}
+	writeErr(w, 400, jsonErrMsg(err))
+}
+
+// jsonErrMsg describes a readJSON failure for the client, naming the
+// offending field when the body carried one the endpoint doesn't accept.
+func jsonErrMsg(err error) string {
+	if err == io.EOF {
+		return "request body is required"
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return "unknown field " + field
+	}
+	return "invalid JSON"
 }
 
 func goalIDFromRequest(r *http.Request) (int64, error) {
 	return strconv.ParseInt(r.PathValue("id"), 10, 64)
 }
 
+// parseTimeParam accepts an RFC3339 timestamp or a YYYY-MM-DD date and
+// returns it in the UTC layout used for stored timestamps. Empty stays empty.
+func parseTimeParam(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, v)
+		if err != nil {
+			return "", err
+		}
+	}
+	return timestamp(t), nil
+}
+
+// goalETag identifies a version of a goal for optimistic concurrency.
+func goalETag(g *Goal) string {
+	return `"` + strconv.FormatInt(g.Version, 10) + `"`
+}
+
+// checkIfMatch enforces an If-Match precondition against the goal's current
+// ETag, writing 412 and returning false when it does not match. On a match
+// it returns the version the write must be conditional on, so a change made
+// after g was read still fails; zero means the write is unconditional.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, g *Goal) (int64, bool) {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return 0, true
+	}
+	current := goalETag(g)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return 0, true
+		}
+		if tag == current {
+			return g.Version, true
+		}
+	}
+	writeErr(w, 412, "goal was modified (current ETag "+current+")")
+	return 0, false
+}
+
+// writeStatusUpdateErr reports a failed status change, as 412 when an
+// If-Match precondition lost a race with another writer.
+func writeStatusUpdateErr(w http.ResponseWriter, err error) {
+	if errors.Is(err, errGoalModified) {
+		writeErr(w, 412, "goal was modified")
+		return
+	}
+	writeErr(w, 500, "failed to update status")
+}
+
+func goalResponse(g *Goal) map[string]any {
+	return map[string]any{
+		"ok":               true,
+		"id":               g.ID,
+		"org":              g.Org,
+		"repo":             g.Repo,
+		"title":            g.Title,
+		"body":             g.Body,
+		"status":           g.Status,
+		"model":            g.Model,
+		"reasoning":        g.Reasoning,
+		"priority":         g.Priority,
+		"created_at":       g.CreatedAt,
+		"updated_at":       g.UpdatedAt,
+		"lease_expires_at": g.LeaseExpiresAt,
+		"stuck_reason":     g.StuckReason,
+		"parent_id":        g.ParentID,
+		"metadata":         g.Metadata,
+	}
+}
+
 // --- handlers ---
 
-func handleCreateGoal(db *sql.DB) http.HandlerFunc {
+// githubNamePattern matches the characters GitHub allows in org and repo names.
+var githubNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// maxTitleLen (in characters) and maxBodyLen (in bytes) bound goal content
+// so a single goal can't hold arbitrarily large text.
+var (
+	maxTitleLen = 500
+	maxBodyLen  = 64 << 10
+)
+
+// fieldErrors maps request fields to what is wrong with each, e.g.
+// {"title": "required"}, so clients can point at the offending input.
+type fieldErrors map[string]string
+
+// add records problem for field unless the field already has one.
+func (f fieldErrors) add(field, problem string) {
+	if _, ok := f[field]; !ok {
+		f[field] = problem
+	}
+}
+
+// message joins the problems into one sentence per field, in field order.
+func (f fieldErrors) message() string {
+	fields := make([]string, 0, len(f))
+	for field := range f {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	var parts []string
+	for _, field := range fields {
+		problem := f[field]
+		if problem == "required" {
+			problem = "is required"
+		}
+		parts = append(parts, field+" "+problem)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// writeFieldErrs writes a 400 listing every invalid field.
+func writeFieldErrs(w http.ResponseWriter, f fieldErrors) {
+	writeJSON(w, 400, map[string]any{"ok": false, "code": codeValidation, "error": f.message(), "fields": f})
+}
+
+// checkContent records a title or body, either of which may be absent, that
+// is blank or too long.
+func (f fieldErrors) checkContent(title, body *string) {
+	if title != nil {
+		if strings.TrimSpace(*title) == "" {
+			f.add("title", "cannot be empty")
+		} else if utf8.RuneCountInString(*title) > maxTitleLen {
+			f.add("title", fmt.Sprintf("must be at most %d characters", maxTitleLen))
+		}
+	}
+	if body != nil && len(*body) > maxBodyLen {
+		f.add("body", fmt.Sprintf("must be at most %d bytes", maxBodyLen))
+	}
+}
+
+// contentError is checkContent as a single message for the client, or "".
+func contentError(title, body *string) string {
+	f := fieldErrors{}
+	f.checkContent(title, body)
+	return f.message()
+}
+
+var validModels = map[string]bool{"haiku": true, "sonnet": true, "opus": true}
+
+var validReasoning = map[string]bool{"none": true, "low": true, "med": true, "high": true}
+
+func handleHealthz(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := store.Ping(ctx); err != nil {
+			writeErr(w, 503, "db unavailable")
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	}
+}
+
+func handleBackup(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The snapshot is complete before the first byte is written, so a failed
+		// snapshot still gets a JSON error instead of a truncated download
+		bw := &deferredWriter{w: w, header: func() {
+			name := "plans-" + time.Now().UTC().Format("2006-01-02T15-04-05") + ".db"
+			w.Header().Set("Content-Type", "application/vnd.sqlite3")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		}}
+		if err := store.Backup(r.Context(), bw); err != nil {
+			log.Printf("request %s: backup failed: %v", requestID(r.Context()), err)
+			if !bw.started {
+				writeErr(w, 500, "failed to back up database")
+			}
+		}
+	}
+}
+
+// deferredWriter sets response headers just before the first write, so a
+// handler can still fall back to an error response if nothing was written.
+type deferredWriter struct {
+	w       http.ResponseWriter
+	header  func()
+	started bool
+}
+
+func (d *deferredWriter) Write(p []byte) (int, error) {
+	if !d.started {
+		d.started = true
+		d.header()
+	}
+	return d.w.Write(p)
+}
+
+func handleCreateGoal(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
-			Org       string  `json:"org"`
-			Repo      string  `json:"repo"`
-			Title     string  `json:"title"`
-			Body      string  `json:"body"`
-			Model     *string `json:"model"`
-			Reasoning *string `json:"reasoning"`
+			Org       string          `json:"org"`
+			Repo      string          `json:"repo"`
+			Title     string          `json:"title"`
+			Body      string          `json:"body"`
+			Model     *string         `json:"model"`
+			Reasoning *string         `json:"reasoning"`
+			Priority  *int            `json:"priority"`
+			ParentID  *int64          `json:"parent_id"`
+			Metadata  json.RawMessage `json:"metadata"`
 		}
 		if err := readJSON(r, &req); err != nil {
-			writeErr(w, 400, "invalid JSON")
+			writeBodyErr(w, err)
 			return
 		}
-		if req.Org == "" || req.Repo == "" || req.Title == "" || req.Body == "" {
-			writeErr(w, 400, "org, repo, title, and body are required")
+		req.Org, req.Repo = strings.TrimSpace(req.Org), strings.TrimSpace(req.Repo)
+		// Every invalid field is reported, not just the first
+		fields := fieldErrors{}
+		for field, v := range map[string]string{"org": req.Org, "repo": req.Repo, "title": req.Title, "body": req.Body} {
+			if v == "" {
+				fields.add(field, "required")
+			}
+		}
+		for field, v := range map[string]string{"org": req.Org, "repo": req.Repo} {
+			if v != "" && !githubNamePattern.MatchString(v) {
+				fields.add(field, "may only contain letters, digits, '.', '_' and '-'")
+			}
+		}
+		fields.checkContent(&req.Title, &req.Body)
+		if req.Model != nil && !validModels[*req.Model] {
+			fields.add("model", "must be one of: haiku, sonnet, opus")
+		}
+		if req.Reasoning != nil && !validReasoning[*req.Reasoning] {
+			fields.add("reasoning", "must be one of: none, low, med, high")
+		}
+		if req.Priority != nil && !validPriority(*req.Priority) {
+			fields.add("priority", "must be between 0 and 100")
+		}
+		metadata, msg := normalizeMetadata(req.Metadata)
+		if msg != "" {
+			fields.add("metadata", strings.TrimPrefix(msg, "metadata "))
+		}
+		if len(fields) > 0 {
+			writeFieldErrs(w, fields)
 			return
 		}
-		// Validate model if provided
-		if req.Model != nil {
-			validModels := map[string]bool{"haiku": true, "sonnet": true, "opus": true}
-			if !validModels[*req.Model] {
-				writeErr(w, 400, "model must be one of: haiku, sonnet, opus")
+		if req.Model == nil || req.Reasoning == nil {
+			d, err := store.GetOrgDefaults(r.Context(), req.Org)
+			if err != nil && err != sql.ErrNoRows {
+				writeErr(w, 500, "failed to get org defaults")
+				return
+			}
+			if d != nil {
+				req.Model = cmp.Or(req.Model, d.Model)
+				req.Reasoning = cmp.Or(req.Reasoning, d.Reasoning)
+			}
+		}
+		if req.ParentID != nil {
+			if _, err := store.GetGoal(r.Context(), *req.ParentID); err == sql.ErrNoRows {
+				writeErrCode(w, 404, codeGoalNotFound, "parent goal not found")
+				return
+			} else if err != nil {
+				writeErr(w, 500, "failed to get parent goal")
 				return
 			}
 		}
-		// Validate reasoning if provided
-		if req.Reasoning != nil {
-			validReasoning := map[string]bool{"none": true, "low": true, "med": true, "high": true}
-			if !validReasoning[*req.Reasoning] {
-				writeErr(w, 400, "reasoning must be one of: none, low, med, high")
+		id, err := store.CreateGoal(r.Context(), NewGoal{
+			Org:       req.Org,
+			Repo:      req.Repo,
+			Title:     req.Title,
+			Body:      req.Body,
+			Model:     req.Model,
+			Reasoning: req.Reasoning,
+			Priority:  req.Priority,
+			ParentID:  req.ParentID,
+			Metadata:  metadata,
+		})
+		if err != nil {
+			writeCreateErr(w, err)
+			return
+		}
+		g, err := store.GetGoal(r.Context(), id)
+		if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		resp, err := goalDetail(r.Context(), store, g)
+		if err != nil {
+			writeErr(w, 500, "failed to check dependencies")
+			return
+		}
+		w.Header().Set("Location", "/goals/"+strconv.FormatInt(id, 10))
+		w.Header().Set("ETag", goalETag(g))
+		writeJSON(w, 201, resp)
+	}
+}
+
+func handleGetGoal(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		g, err := store.GetGoal(r.Context(), id)
+		if err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		}
+		if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+
+		resp, err := goalDetail(r.Context(), store, g)
+		if err != nil {
+			writeErr(w, 500, "failed to check dependencies")
+			return
+		}
+		w.Header().Set("ETag", goalETag(g))
+		writeJSON(w, 200, resp)
+	}
+}
+
+// goalDetail extends goalResponse with the dependency-aware fields returned
+// for a single goal.
+func goalDetail(ctx context.Context, store Store, g *Goal) (map[string]any, error) {
+	// Terminal goals can't be blocked, so skip the dependency query for them
+	unmet := 0
+	if !isTerminal(g.Status) {
+		var err error
+		unmet, err = store.CountUnmetDependencies(ctx, g.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	allowed := []string{}
+	for _, to := range validTransitions[g.Status] {
+		// Starting is gated on dependencies, so don't advertise it while any are unmet
+		if to == "running" && unmet > 0 {
+			continue
+		}
+		allowed = append(allowed, to)
+	}
+
+	resp := goalResponse(g)
+	resp["allowed_transitions"] = allowed
+	resp["unmet_dependencies"] = unmet
+	resp["blocked"] = unmet > 0
+	return resp, nil
+}
+
+// statusList splits a comma-separated status parameter, reporting false if
+// any entry is not a known status. An empty parameter gives nil.
+func statusList(param string) ([]string, bool) {
+	if param == "" {
+		return nil, true
+	}
+	list := strings.Split(param, ",")
+	for i, st := range list {
+		list[i] = strings.TrimSpace(st)
+		if !slices.Contains(statuses, list[i]) {
+			return nil, false
+		}
+	}
+	return list, true
+}
+
+// goalFilterFromRequest reads the GET /goals filter and sort parameters,
+// writing a 400 and returning false if any is invalid.
+func goalFilterFromRequest(w http.ResponseWriter, r *http.Request) (GoalFilter, bool) {
+	filter := GoalFilter{
+		Org:       r.URL.Query().Get("org"),
+		Repo:      r.URL.Query().Get("repo"),
+		Ready:     r.URL.Query().Get("ready") == "true",
+		Blocked:   r.URL.Query().Get("blocked") == "true",
+		Query:     r.URL.Query().Get("q"),
+		Tag:       normalizeTag(r.URL.Query().Get("tag")),
+		Model:     r.URL.Query().Get("model"),
+		Reasoning: r.URL.Query().Get("reasoning"),
+		Sort:      r.URL.Query().Get("sort"),
+		Order:     r.URL.Query().Get("order"),
+	}
+	if filter.Ready && filter.Blocked {
+		writeErr(w, 400, "ready and blocked cannot both be true")
+		return GoalFilter{}, false
+	}
+	var ok bool
+	if filter.Statuses, ok = statusList(r.URL.Query().Get("status")); !ok {
+		writeErr(w, 400, "status must be a comma-separated list of: "+strings.Join(statuses, ", "))
+		return GoalFilter{}, false
+	}
+	// Only queued goals can be claimed, so readiness means nothing for others
+	if filter.Ready {
+		if filter.Statuses == nil {
+			filter.Statuses = []string{"queued"}
+		} else if !slices.Equal(filter.Statuses, []string{"queued"}) {
+			writeErr(w, 400, "ready=true only applies to queued goals; use status=queued or omit status")
+			return GoalFilter{}, false
+		}
+	}
+	if filter.Excluded, ok = statusList(r.URL.Query().Get("exclude_status")); !ok {
+		writeErr(w, 400, "exclude_status must be a comma-separated list of: "+strings.Join(statuses, ", "))
+		return GoalFilter{}, false
+	}
+	var err error
+	if filter.CreatedAfter, err = parseTimeParam(r.URL.Query().Get("created_after")); err != nil {
+		writeErr(w, 400, "created_after must be an RFC3339 timestamp or YYYY-MM-DD date")
+		return GoalFilter{}, false
+	}
+	if filter.CreatedBefore, err = parseTimeParam(r.URL.Query().Get("created_before")); err != nil {
+		writeErr(w, 400, "created_before must be an RFC3339 timestamp or YYYY-MM-DD date")
+		return GoalFilter{}, false
+	}
+	if filter.Model != "" && filter.Model != unsetFilter && !validModels[filter.Model] {
+		writeErr(w, 400, "model must be one of: haiku, sonnet, opus, unset")
+		return GoalFilter{}, false
+	}
+	if filter.Reasoning != "" && filter.Reasoning != unsetFilter && !validReasoning[filter.Reasoning] {
+		writeErr(w, 400, "reasoning must be one of: none, low, med, high, unset")
+		return GoalFilter{}, false
+	}
+	if _, ok := goalSortColumns[filter.Sort]; filter.Sort != "" && !ok {
+		writeErr(w, 400, "sort must be one of: id, created_at, updated_at")
+		return GoalFilter{}, false
+	}
+	if filter.Order != "" && filter.Order != "asc" && filter.Order != "desc" {
+		writeErr(w, 400, "order must be one of: asc, desc")
+		return GoalFilter{}, false
+	}
+	return filter, true
+}
+
+func handleListGoals(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, ok := goalFilterFromRequest(w, r)
+		if !ok {
+			return
+		}
+
+		// Cursor mode: keyset pagination on id, independent of page/per_page
+		if r.URL.Query().Has("cursor") {
+			if filter.Sort != "" || filter.Order != "" {
+				writeErr(w, 400, "sort and order are not supported with cursor")
 				return
 			}
+
+			var cursor int64
+			if cursorStr := r.URL.Query().Get("cursor"); cursorStr != "" {
+				var err error
+				cursor, err = strconv.ParseInt(cursorStr, 10, 64)
+				if err != nil || cursor <= 0 {
+					writeErr(w, 400, "cursor must be a positive integer")
+					return
+				}
+			}
+
+			limit := 20 // default
+			if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+				var err error
+				limit, err = strconv.Atoi(limitStr)
+				if err != nil || limit <= 0 {
+					writeErr(w, 400, "limit must be a positive integer")
+					return
+				}
+			}
+			if limit > 100 {
+				limit = 100
+			}
+
+			goals, more, err := store.ListGoalsAfter(r.Context(), filter, cursor, limit)
+			if err != nil {
+				writeErr(w, 500, "failed to list goals")
+				return
+			}
+			if goals == nil {
+				goals = []GoalSummary{}
+			}
+
+			var nextCursor *int64
+			if more {
+				nextCursor = &goals[len(goals)-1].ID
+			}
+			writeJSON(w, 200, map[string]any{
+				"ok":          true,
+				"items":       goals,
+				"limit":       limit,
+				"next_cursor": nextCursor,
+			})
+			return
+		}
+
+		page, perPage, ok := readPage(w, r)
+		if !ok {
+			return
+		}
+		paginated := page > 0
+
+		goals, total, err := store.ListGoals(r.Context(), filter, perPage, (page-1)*perPage)
+		if err != nil {
+			writeErr(w, 500, "failed to list goals")
+			return
+		}
+		if goals == nil {
+			goals = []GoalSummary{}
+		}
+
+		if paginated {
+			totalPages := (total + perPage - 1) / perPage
+			writeJSON(w, 200, map[string]any{
+				"ok":          true,
+				"items":       goals,
+				"page":        page,
+				"per_page":    perPage,
+				"total":       total,
+				"total_pages": totalPages,
+				"has_next":    page < totalPages,
+				"has_prev":    page > 1,
+			})
+		} else {
+			writeJSON(w, 200, map[string]any{"ok": true, "items": goals})
+		}
+	}
+}
+
+func handleGoalStats(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		counts, err := store.GoalStats(r.Context(), r.URL.Query().Get("org"), r.URL.Query().Get("repo"))
+		if err != nil {
+			writeErr(w, 500, "failed to get stats")
+			return
+		}
+		total := 0
+		for _, n := range counts {
+			total += n
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "counts": counts, "total": total})
+	}
+}
+
+// handleCycleTime reports how long done goals took from first being queued
+// to done, overall and, with group_by=org or group_by=repo, per group.
+func handleCycleTime(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		groupBy := q.Get("group_by")
+		if groupBy != "" && groupBy != "org" && groupBy != "repo" {
+			writeErr(w, 400, "group_by must be one of: org, repo")
+			return
+		}
+		after, err := parseTimeParam(q.Get("done_after"))
+		if err != nil {
+			writeErr(w, 400, "done_after must be an RFC3339 timestamp or YYYY-MM-DD date")
+			return
+		}
+		before, err := parseTimeParam(q.Get("done_before"))
+		if err != nil {
+			writeErr(w, 400, "done_before must be an RFC3339 timestamp or YYYY-MM-DD date")
+			return
+		}
+		times, err := store.CycleTimes(r.Context(), q.Get("org"), q.Get("repo"), after, before)
+		if err != nil {
+			writeErr(w, 500, "failed to get cycle times")
+			return
+		}
+
+		all := make([]int64, 0, len(times))
+		for _, ct := range times {
+			all = append(all, ct.Seconds)
+		}
+		resp := map[string]any{"ok": true, "cycle_time": durationStats(all)}
+		if groupBy != "" {
+			// times is ordered by org then repo, so each group is a run
+			groups := []map[string]any{}
+			for i := 0; i < len(times); {
+				j, group := i, []int64{}
+				for ; j < len(times) && times[j].Org == times[i].Org && (groupBy == "org" || times[j].Repo == times[i].Repo); j++ {
+					group = append(group, times[j].Seconds)
+				}
+				g := map[string]any{"org": times[i].Org, "cycle_time": durationStats(group)}
+				if groupBy == "repo" {
+					g["repo"] = times[i].Repo
+				}
+				groups = append(groups, g)
+				i = j
+			}
+			resp["groups"] = groups
 		}
-		id, err := createGoal(db, req.Org, req.Repo, req.Title, req.Body, req.Model, req.Reasoning)
+		writeJSON(w, 200, resp)
+	}
+}
+
+// handleGoalOrder lists the goals with ?status= (default queued) in an order
+// that runs every goal after its dependencies within that set, or reports a
+// dependency cycle with 409.
+func handleGoalOrder(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := cmp.Or(r.URL.Query().Get("status"), "queued")
+		if !slices.Contains(statuses, status) {
+			writeErr(w, 400, "status must be one of: "+strings.Join(statuses, ", "))
+			return
+		}
+		goals, deps, err := store.DependencyGraph(r.Context(), status)
 		if err != nil {
-			writeErr(w, 500, "failed to create goal")
+			writeErr(w, 500, "failed to load dependencies")
+			return
+		}
+		order, cycle := topoOrder(goals, deps)
+		if cycle != nil {
+			writeJSON(w, 409, map[string]any{"ok": false, "code": codeDependencyCycle, "error": "dependency cycle", "cycle": cycle})
+			return
+		}
+		if order == nil {
+			order = []GoalSummary{}
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "items": order})
+	}
+}
+
+func handleUpdateGoal(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		g, err := store.GetGoal(r.Context(), id)
+		if err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		}
+		if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		if isTerminal(g.Status) {
+			writeErr(w, 409, (&notEditableError{Status: g.Status}).Error())
+			return
+		}
+		var req struct {
+			Title     *string         `json:"title"`
+			Body      *string         `json:"body"`
+			Model     json.RawMessage `json:"model"`
+			Reasoning json.RawMessage `json:"reasoning"`
+			ParentID  json.RawMessage `json:"parent_id"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			writeBodyErr(w, err)
+			return
+		}
+		if req.Body != nil && *req.Body == "" {
+			writeErr(w, 400, "body cannot be empty")
+			return
+		}
+		if msg := contentError(req.Title, req.Body); msg != "" {
+			writeErr(w, 400, msg)
+			return
+		}
+		u := GoalUpdate{Title: req.Title, Body: req.Body}
+		if u.Model, err = nullableChoice(req.Model, validModels); err != nil {
+			writeErr(w, 400, "model must be null or one of: haiku, sonnet, opus")
+			return
+		}
+		if u.Reasoning, err = nullableChoice(req.Reasoning, validReasoning); err != nil {
+			writeErr(w, 400, "reasoning must be null or one of: none, low, med, high")
+			return
+		}
+		if len(req.ParentID) > 0 {
+			var parentID *int64
+			if err := json.Unmarshal(req.ParentID, &parentID); err != nil {
+				writeErr(w, 400, "parent_id must be a goal id or null")
+				return
+			}
+			if parentID != nil && *parentID == id {
+				writeErr(w, 400, "goal cannot be its own parent")
+				return
+			}
+			u.ParentID = &parentID
+		}
+		// Switching model or reasoning mid-run would not affect the worker
+		if (u.Model != nil || u.Reasoning != nil) && g.Status != "draft" && g.Status != "queued" {
+			writeErr(w, 409, (&notEditableError{Status: g.Status, ModelOrReasoning: true}).Error())
+			return
+		}
+		if err := store.UpdateGoal(r.Context(), id, u); err != nil {
+			writeUpdateGoalErr(w, err)
+			return
+		}
+		g, err = store.GetGoal(r.Context(), id)
+		if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		writeJSON(w, 200, goalResponse(g))
+	}
+}
+
+// errInvalidChoice is returned by nullableChoice for a value outside the
+// allowed set.
+var errInvalidChoice = errors.New("invalid choice")
+
+// nullableChoice decodes an optional field that may be set to one of allowed
+// or cleared with null. It returns nil when the field was omitted, and a
+// pointer to nil when it was null.
+func nullableChoice(raw json.RawMessage, allowed map[string]bool) (**string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var v *string
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, errInvalidChoice
+	}
+	if v != nil && !allowed[*v] {
+		return nil, errInvalidChoice
+	}
+	return &v, nil
+}
+
+func validPriority(p int) bool {
+	return p >= 0 && p <= 100
+}
+
+// normalizeMetadata validates metadata supplied at creation and re-encodes
+// it compactly. Absent or null metadata yields nil. On failure it returns a
+// message for the client.
+func normalizeMetadata(raw json.RawMessage) (json.RawMessage, string) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, ""
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, "metadata must be a JSON object"
+	}
+	out, err := json.Marshal(m)
+	if err != nil {
+		return nil, "metadata must be a JSON object"
+	}
+	if len(out) > maxMetadataBytes {
+		return nil, errMetadataTooLarge.Error()
+	}
+	return out, ""
+}
+
+func handleMergeMetadata(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		// The body is the object to merge; a null value removes that key
+		var patch map[string]json.RawMessage
+		if err := readJSON(r, &patch); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				writeErr(w, 400, "metadata must be a JSON object")
+				return
+			}
+			writeBodyErr(w, err)
+			return
+		}
+		if patch == nil {
+			writeErr(w, 400, "metadata must be a JSON object")
+			return
+		}
+		merged, err := store.MergeGoalMetadata(r.Context(), id, patch)
+		if errors.Is(err, errMetadataTooLarge) {
+			writeErr(w, 400, err.Error())
+			return
+		}
+		if err != nil {
+			writeErr(w, 500, "failed to update metadata")
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "metadata": merged})
+	}
+}
+
+func handleSetPriority(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		// Raw so that an explicit null (clear the priority) is told apart from
+		// a missing field
+		var req struct {
+			Priority json.RawMessage `json:"priority"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			writeBodyErr(w, err)
+			return
+		}
+		if len(req.Priority) == 0 {
+			writeErr(w, 400, "priority is required (null clears it)")
+			return
+		}
+		var priority *int
+		if err := json.Unmarshal(req.Priority, &priority); err != nil || (priority != nil && !validPriority(*priority)) {
+			writeErr(w, 400, "priority must be null or between 0 and 100")
+			return
+		}
+		if err := store.UpdateGoalPriority(r.Context(), id, priority); err != nil {
+			writeErr(w, 500, "failed to update priority")
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "priority": priority})
+	}
+}
+
+// handleCloneGoal creates a new draft from a goal's org, repo, title, body,
+// model and reasoning. The optional body overrides any of the last four;
+// status, comments, tags and dependencies are not copied.
+func handleCloneGoal(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		src, err := store.GetGoal(r.Context(), id)
+		if err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		}
+		if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		var req struct {
+			Title     *string         `json:"title"`
+			Body      *string         `json:"body"`
+			Model     json.RawMessage `json:"model"`
+			Reasoning json.RawMessage `json:"reasoning"`
+		}
+		if err := readJSON(r, &req); err != nil && err != io.EOF {
+			writeBodyErr(w, err)
+			return
+		}
+		if req.Body != nil && *req.Body == "" {
+			writeErr(w, 400, "body cannot be empty")
+			return
+		}
+		if msg := contentError(req.Title, req.Body); msg != "" {
+			writeErr(w, 400, msg)
+			return
+		}
+		model, err := nullableChoice(req.Model, validModels)
+		if err != nil {
+			writeErr(w, 400, "model must be null or one of: haiku, sonnet, opus")
+			return
+		}
+		reasoning, err := nullableChoice(req.Reasoning, validReasoning)
+		if err != nil {
+			writeErr(w, 400, "reasoning must be null or one of: none, low, med, high")
+			return
+		}
+		ng := NewGoal{
+			Org:       src.Org,
+			Repo:      src.Repo,
+			Title:     *cmp.Or(req.Title, &src.Title),
+			Body:      *cmp.Or(req.Body, &src.Body),
+			Model:     src.Model,
+			Reasoning: src.Reasoning,
+		}
+		if model != nil {
+			ng.Model = *model
+		}
+		if reasoning != nil {
+			ng.Reasoning = *reasoning
+		}
+		newID, err := store.CreateGoal(r.Context(), ng)
+		if err != nil {
+			writeCreateErr(w, err)
+			return
+		}
+		g, err := store.GetGoal(r.Context(), newID)
+		if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		resp, err := goalDetail(r.Context(), store, g)
+		if err != nil {
+			writeErr(w, 500, "failed to check dependencies")
+			return
+		}
+		w.Header().Set("Location", "/goals/"+strconv.FormatInt(newID, 10))
+		w.Header().Set("ETag", goalETag(g))
+		writeJSON(w, 201, resp)
+	}
+}
+
+func handleDeleteGoal(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		// Refuse to orphan dependencies unless explicitly forced
+		if r.URL.Query().Get("force") != "true" {
+			dependents, err := store.HasDependents(r.Context(), id)
+			if err != nil {
+				writeErr(w, 500, "failed to check dependents")
+				return
+			}
+			if dependents {
+				writeErr(w, 409, "other goals depend on this goal (use force=true to delete anyway)")
+				return
+			}
+		}
+		if err := store.DeleteGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to delete goal")
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	}
+}
+
+// leaseDuration is how long a claim or heartbeat keeps a running goal leased.
+var leaseDuration = 5 * time.Minute
+
+func handleClaimNext(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The body is optional; an empty one claims from any org/repo
+		var req struct {
+			Org  string `json:"org"`
+			Repo string `json:"repo"`
+		}
+		if err := readJSON(r, &req); err != nil && err != io.EOF {
+			writeBodyErr(w, err)
+			return
+		}
+		g, err := store.ClaimNextGoal(r.Context(), req.Org, req.Repo, leaseDuration)
+		if err == sql.ErrNoRows {
+			w.WriteHeader(204)
+			return
+		}
+		if err != nil {
+			writeErr(w, 500, "failed to claim goal")
+			return
+		}
+		writeJSON(w, 200, goalResponse(g))
+	}
+}
+
+func handleRequeueStuck(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The body is optional; an empty one requeues stuck goals in every org/repo
+		var req struct {
+			Org  string `json:"org"`
+			Repo string `json:"repo"`
+		}
+		if err := readJSON(r, &req); err != nil && err != io.EOF {
+			writeBodyErr(w, err)
+			return
+		}
+		requeued, skipped, err := store.RequeueStuckGoals(r.Context(), req.Org, req.Repo, maxRetries)
+		if err != nil {
+			writeErr(w, 500, "failed to requeue goals")
+			return
+		}
+		if requeued == nil {
+			requeued = []int64{}
+		}
+		if skipped == nil {
+			skipped = []int64{}
+		}
+		writeJSON(w, 200, map[string]any{
+			"ok":       true,
+			"count":    len(requeued),
+			"requeued": requeued,
+			"skipped":  skipped,
+		})
+	}
+}
+
+func handleHeartbeat(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		g, err := store.GetGoal(r.Context(), id)
+		if err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		}
+		if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		if g.Status != "running" {
+			writeErr(w, 409, "cannot heartbeat a goal that is "+g.Status)
+			return
+		}
+		expires, err := store.ExtendLease(r.Context(), id, leaseDuration)
+		if err == sql.ErrNoRows {
+			writeErr(w, 409, "goal is no longer running")
+			return
+		}
+		if err != nil {
+			writeErr(w, 500, "failed to extend lease")
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "lease_expires_at": expires})
+	}
+}
+
+func handleQueue(store Store) http.HandlerFunc {
+	return transitionHandler(store, "queued")
+}
+
+func handleStart(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		g, err := store.GetGoal(r.Context(), id)
+		if err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		}
+		if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		version, ok := checkIfMatch(w, r, g)
+		if !ok {
+			return
+		}
+		req, ok := readTransitionBody(w, r, "running")
+		if !ok {
+			return
+		}
+		if !canTransition(g.Status, "running") {
+			writeErrCode(w, 409, codeInvalidTransition, transitionError(g.Status, "running"))
+			return
+		}
+		if !cancelledDepsSatisfied {
+			depID, err := store.CancelledDependency(r.Context(), id)
+			if err == nil {
+				writeErrCode(w, 409, codeUnmetDependencies, "dependency "+strconv.FormatInt(depID, 10)+" was cancelled and can never be satisfied")
+				return
+			}
+			if err != sql.ErrNoRows {
+				writeErr(w, 500, "failed to check dependencies")
+				return
+			}
+		}
+		unmet, err := store.HasUnmetDependencies(r.Context(), id)
+		if err != nil {
+			writeErr(w, 500, "failed to check dependencies")
+			return
+		}
+		if unmet {
+			writeErrCode(w, 409, codeUnmetDependencies, "goal has unmet dependencies")
+			return
+		}
+		if err := store.TransitionGoal(r.Context(), id, g.Status, "running", version, req.Note); err != nil {
+			writeStatusUpdateErr(w, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	}
+}
+
+func handleDone(store Store) http.HandlerFunc {
+	return transitionHandler(store, "done")
+}
+
+func handleStuck(store Store) http.HandlerFunc {
+	return transitionHandler(store, "stuck")
+}
+
+// maxRetries caps how many times a stuck goal may be requeued.
+var maxRetries = 3
+
+func handleRequeue(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		g, err := store.GetGoal(r.Context(), id)
+		if err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		}
+		if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		version, ok := checkIfMatch(w, r, g)
+		if !ok {
+			return
+		}
+		req, ok := readTransitionBody(w, r, "queued")
+		if !ok {
+			return
+		}
+		if !canTransition(g.Status, "queued") {
+			writeErrCode(w, 409, codeInvalidTransition, transitionError(g.Status, "queued"))
+			return
+		}
+		if g.Status != "stuck" {
+			writeErrCode(w, 409, codeInvalidTransition, "only stuck goals can be requeued; queue a "+g.Status+" goal with /queue")
+			return
+		}
+		writeRequeue(w, r, store, g, version, req.Note)
+	}
+}
+
+// writeRequeue moves a stuck goal back to queued, enforcing the retry cap.
+// It backs both /requeue and the generic stuck->queued transition so the
+// cap cannot be sidestepped via /queue.
+func writeRequeue(w http.ResponseWriter, r *http.Request, store Store, g *Goal, version int64, note *string) {
+	retries, err := store.RequeueGoal(r.Context(), g.ID, maxRetries, version, note)
+	if errors.Is(err, errRetryLimit) {
+		writeErr(w, 409, "goal has reached the retry limit of "+strconv.Itoa(maxRetries))
+		return
+	}
+	if err != nil {
+		writeStatusUpdateErr(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"ok": true, "retries": retries})
+}
+
+func handleCancel(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		g, err := store.GetGoal(r.Context(), id)
+		if err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		}
+		if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		version, ok := checkIfMatch(w, r, g)
+		if !ok {
+			return
+		}
+		req, ok := readTransitionBody(w, r, "cancelled")
+		if !ok {
+			return
+		}
+		if !canTransition(g.Status, "cancelled") {
+			writeErrCode(w, 409, codeInvalidTransition, transitionError(g.Status, "cancelled"))
+			return
+		}
+		if r.URL.Query().Get("cascade") == "true" {
+			cascaded, err := store.CancelGoalCascade(r.Context(), id, g.Status, version, req.Note)
+			if err != nil {
+				writeStatusUpdateErr(w, err)
+				return
+			}
+			writeJSON(w, 200, map[string]any{"ok": true, "cascaded": cascaded})
+			return
+		}
+		if err := store.TransitionGoal(r.Context(), id, g.Status, "cancelled", version, req.Note); err != nil {
+			writeStatusUpdateErr(w, err)
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true})
+	}
+}
+
+func handleListTransitions(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		transitions, err := store.ListTransitions(r.Context(), id)
+		if err != nil {
+			writeErr(w, 500, "failed to list transitions")
+			return
+		}
+		if transitions == nil {
+			transitions = []Transition{}
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "items": transitions})
+	}
+}
+
+// handleGoalTiming reports how many seconds the goal has spent in each
+// status, from its transition history.
+func handleGoalTiming(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		transitions, err := store.ListTransitions(r.Context(), id)
+		if err != nil {
+			writeErr(w, 500, "failed to list transitions")
+			return
+		}
+		seconds, err := statusDurations(transitions, time.Now())
+		if err != nil {
+			writeErr(w, 500, "failed to read transition times")
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "seconds": seconds})
+	}
+}
+
+// changesDefaultLimit and changesMaxLimit bound how many transitions one
+// GET /changes returns.
+const (
+	changesDefaultLimit = 100
+	changesMaxLimit     = 1000
+)
+
+// handleListChanges serves the change feed: transitions after ?since= (a
+// transition id, default 0) in id order. cursor is the last id returned, or
+// since when there is nothing new, so clients poll from it next time.
+func handleListChanges(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var since int64
+		if v := q.Get("since"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				writeErr(w, 400, "since must be a non-negative transition id")
+				return
+			}
+			since = n
+		}
+		limit := changesDefaultLimit
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				writeErr(w, 400, "limit must be a positive integer")
+				return
+			}
+			limit = min(n, changesMaxLimit)
+		}
+		changes, more, err := store.ListChanges(r.Context(), since, limit)
+		if err != nil {
+			writeErr(w, 500, "failed to list changes")
+			return
+		}
+		cursor := since
+		if len(changes) > 0 {
+			cursor = changes[len(changes)-1].ID
+		} else {
+			changes = []Change{}
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "items": changes, "cursor": cursor, "more": more})
+	}
+}
+
+// longPollDefaultTimeout and longPollMaxTimeout bound how long a long-poll
+// waits for a change before answering with an empty list.
+const (
+	longPollDefaultTimeout = 30 * time.Second
+	longPollMaxTimeout     = 60 * time.Second
+)
+
+// handleLongPollChanges answers like GET /changes, but when nothing is newer
+// than ?since= it waits up to ?timeout= for a transition before replying. A
+// timeout returns no items and the unchanged cursor.
+func handleLongPollChanges(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var since int64
+		if v := q.Get("since"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				writeErr(w, 400, "since must be a non-negative transition id")
+				return
+			}
+			since = n
+		}
+		timeout := longPollDefaultTimeout
+		if v := q.Get("timeout"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				writeErr(w, 400, "timeout must be a positive duration (e.g. 30s)")
+				return
+			}
+			timeout = min(d, longPollMaxTimeout)
+		}
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		for {
+			wake := transitionsChanged.wait()
+			changes, more, err := store.ListChanges(r.Context(), since, changesDefaultLimit)
+			if err != nil {
+				if r.Context().Err() != nil {
+					return
+				}
+				writeErr(w, 500, "failed to list changes")
+				return
+			}
+			if len(changes) > 0 {
+				cursor := changes[len(changes)-1].ID
+				writeJSON(w, 200, map[string]any{"ok": true, "items": changes, "cursor": cursor, "more": more})
+				return
+			}
+			select {
+			case <-wake:
+				continue
+			case <-timer.C:
+			case <-r.Context().Done():
+				// A client that went away needs no answer; one cut short by
+				// RALPH_REQUEST_TIMEOUT gets the same reply as a timeout
+				if r.Context().Err() != context.DeadlineExceeded {
+					return
+				}
+			}
+			writeJSON(w, 200, map[string]any{"ok": true, "items": []Change{}, "cursor": since, "more": false})
+			return
+		}
+	}
+}
+
+// readPage parses the optional page/per_page query parameters. page is 0
+// when the request is unpaginated; per_page defaults to 20 and is clamped
+// to 100. On a bad value it writes a 400 and returns false.
+func readPage(w http.ResponseWriter, r *http.Request) (page, perPage int, ok bool) {
+	pageStr := r.URL.Query().Get("page")
+	if pageStr == "" {
+		return 0, 0, true
+	}
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page <= 0 {
+		writeErr(w, 400, "page must be a positive integer")
+		return 0, 0, false
+	}
+
+	perPage = 20 // default
+	if perPageStr := r.URL.Query().Get("per_page"); perPageStr != "" {
+		perPage, err = strconv.Atoi(perPageStr)
+		if err != nil || perPage <= 0 {
+			writeErr(w, 400, "per_page must be a positive integer")
+			return 0, 0, false
+		}
+	}
+	if perPage > 100 {
+		perPage = 100
+	}
+	return page, perPage, true
+}
+
+func handleListActivity(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		page, perPage, ok := readPage(w, r)
+		if !ok {
+			return
+		}
+		items, total, err := store.ListActivity(r.Context(), id, perPage, (page-1)*perPage)
+		if err != nil {
+			writeErr(w, 500, "failed to list activity")
+			return
+		}
+		if items == nil {
+			items = []ActivityItem{}
+		}
+		if page > 0 {
+			writeJSON(w, 200, map[string]any{
+				"ok":       true,
+				"items":    items,
+				"page":     page,
+				"per_page": perPage,
+				"total":    total,
+			})
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "items": items})
+	}
+}
+
+func handleCreateComment(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		// Verify goal exists
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			writeBodyErr(w, err)
+			return
+		}
+		if req.Body == "" {
+			writeErr(w, 400, "body is required")
+			return
+		}
+		cid, err := store.CreateComment(r.Context(), id, req.Body)
+		if err != nil {
+			writeErr(w, 500, "failed to create comment")
+			return
+		}
+		writeJSON(w, 201, map[string]any{"ok": true, "id": cid, "goal_id": id})
+	}
+}
+
+func handleListComments(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		comments, err := store.ListComments(r.Context(), id)
+		if err != nil {
+			writeErr(w, 500, "failed to list comments")
+			return
+		}
+		if comments == nil {
+			comments = []Comment{}
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "items": comments})
+	}
+}
+
+func handleEditComment(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := goalIDFromRequest(r)
+		if err != nil {
+			writeErr(w, 400, "invalid goal id")
+			return
+		}
+		commentID, err := strconv.ParseInt(r.PathValue("comment_id"), 10, 64)
+		if err != nil {
+			writeErr(w, 400, "invalid comment_id")
+			return
+		}
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			writeBodyErr(w, err)
+			return
+		}
+		if req.Body == "" {
+			writeErr(w, 400, "body is required")
+			return
+		}
+		if err := store.UpdateComment(r.Context(), id, commentID, req.Body); err == sql.ErrNoRows {
+			writeErr(w, 404, "comment not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to edit comment")
 			return
 		}
-		writeJSON(w, 201, map[string]any{"ok": true, "id": id})
+		writeJSON(w, 200, map[string]any{"ok": true})
 	}
 }
 
-func handleGetGoal(db *sql.DB) http.HandlerFunc {
+func handleDeleteComment(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		g, err := getGoal(db, id)
-		if err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
-			return
-		}
+		commentID, err := strconv.ParseInt(r.PathValue("comment_id"), 10, 64)
 		if err != nil {
-			writeErr(w, 500, "failed to get goal")
+			writeErr(w, 400, "invalid comment_id")
 			return
 		}
-
-		writeJSON(w, 200, map[string]any{
-			"ok":         true,
-			"id":         g.ID,
-			"org":        g.Org,
-			"repo":       g.Repo,
-			"title":      g.Title,
-			"body":       g.Body,
-			"status":     g.Status,
-			"model":      g.Model,
-			"reasoning":  g.Reasoning,
-			"created_at": g.CreatedAt,
-			"updated_at": g.UpdatedAt,
-		})
-	}
-}
-
-func handleListGoals(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		status := r.URL.Query().Get("status")
-		org := r.URL.Query().Get("org")
-		repo := r.URL.Query().Get("repo")
-		ready := r.URL.Query().Get("ready") == "true"
-
-		// Parse pagination parameters
-		pageStr := r.URL.Query().Get("page")
-		perPageStr := r.URL.Query().Get("per_page")
-
-		var limit, offset int
-		var page, perPage int
-		paginated := pageStr != ""
-
-		if paginated {
-			var err error
-			page, err = strconv.Atoi(pageStr)
-			if err != nil || page <= 0 {
-				writeErr(w, 400, "page must be a positive integer")
-				return
-			}
-
-			perPage = 20 // default
-			if perPageStr != "" {
-				perPage, err = strconv.Atoi(perPageStr)
-				if err != nil || perPage <= 0 {
-					writeErr(w, 400, "per_page must be a positive integer")
-					return
-				}
-			}
-
-			// Clamp per_page to max 100
-			if perPage > 100 {
-				perPage = 100
-			}
-
-			limit = perPage
-			offset = (page - 1) * perPage
-		}
-
-		goals, total, err := listGoals(db, status, org, repo, ready, limit, offset)
-		if err != nil {
-			writeErr(w, 500, "failed to list goals")
+		if err := store.DeleteComment(r.Context(), id, commentID); err == sql.ErrNoRows {
+			writeErr(w, 404, "comment not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to delete comment")
 			return
 		}
-		if goals == nil {
-			goals = []GoalSummary{}
-		}
-
-		if paginated {
-			writeJSON(w, 200, map[string]any{
-				"ok":       true,
-				"items":    goals,
-				"page":     page,
-				"per_page": perPage,
-				"total":    total,
-			})
-		} else {
-			writeJSON(w, 200, map[string]any{"ok": true, "items": goals})
-		}
+		writeJSON(w, 200, map[string]any{"ok": true})
 	}
 }
 
-func handleQueue(db *sql.DB) http.HandlerFunc {
-	return transitionHandler(db, "draft", "queued")
+// dependencyAllowedStatuses are the statuses that allow adding/removing dependencies.
+var dependencyAllowedStatuses = map[string]bool{
+	"draft":  true,
+	"queued": true,
+	"stuck":  true,
 }
 
-func handleStart(db *sql.DB) http.HandlerFunc {
+func handleAddDependency(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		g, err := getGoal(db, id)
+		g, err := store.GetGoal(r.Context(), id)
 		if err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
 			return
 		}
 		if err != nil {
 			writeErr(w, 500, "failed to get goal")
 			return
 		}
-		if g.Status != "queued" {
-			writeErr(w, 409, "cannot transition from "+g.Status+" to running")
+		if !dependencyAllowedStatuses[g.Status] {
+			writeErr(w, 409, "cannot modify dependencies when goal is "+g.Status)
 			return
 		}
-		unmet, err := hasUnmetDependencies(db, id)
-		if err != nil {
-			writeErr(w, 500, "failed to check dependencies")
+		var req struct {
+			DependsOnID  int64   `json:"depends_on_id"`
+			DependsOnIDs []int64 `json:"depends_on_ids"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			writeBodyErr(w, err)
 			return
 		}
-		if unmet {
-			writeErr(w, 409, "goal has unmet dependencies")
+		if req.DependsOnIDs != nil {
+			if req.DependsOnID != 0 {
+				writeErr(w, 400, "send depends_on_id or depends_on_ids, not both")
+				return
+			}
+			addDependencyBatch(w, r, store, id, req.DependsOnIDs)
 			return
 		}
-		if err := updateGoalStatus(db, id, "queued", "running"); err != nil {
-			writeErr(w, 500, "failed to update status")
+		if req.DependsOnID == 0 {
+			writeErr(w, 400, "depends_on_id is required")
 			return
 		}
-		writeJSON(w, 200, map[string]any{"ok": true})
+		addDependencyBatch(w, r, store, id, []int64{req.DependsOnID})
 	}
 }
 
-func handleDone(db *sql.DB) http.HandlerFunc {
-	return transitionHandler(db, "running", "done")
-}
-
-func handleStuck(db *sql.DB) http.HandlerFunc {
-	return transitionHandler(db, "running", "stuck")
-}
-
-func handleRequeue(db *sql.DB) http.HandlerFunc {
-	return transitionHandler(db, "stuck", "queued")
+// addDependencyBatch adds every id in ids as a dependency of goal id, or
+// none of them if any is invalid, reporting the offending id.
+func addDependencyBatch(w http.ResponseWriter, r *http.Request, store Store, id int64, ids []int64) {
+	if len(ids) == 0 {
+		writeErr(w, 400, "depends_on_ids must not be empty")
+		return
+	}
+	for _, dep := range ids {
+		if dep == id {
+			writeErr(w, 400, "goal cannot depend on itself")
+			return
+		}
+	}
+	err := store.AddDependencies(r.Context(), id, ids)
+	var depErr *dependencyError
+	switch {
+	case errors.As(err, &depErr) && depErr.Err == errDependencyNotFound:
+		writeJSON(w, 404, map[string]any{"ok": false, "code": codeGoalNotFound, "error": depErr.Err.Error(), "depends_on_id": depErr.DependsOnID})
+	case errors.As(err, &depErr):
+		code := codeConflict
+		if depErr.Err == errDependencyCycle {
+			code = codeDependencyCycle
+		}
+		writeJSON(w, 409, map[string]any{"ok": false, "code": code, "error": depErr.Err.Error(), "depends_on_id": depErr.DependsOnID})
+	case err != nil:
+		writeErr(w, 500, "failed to add dependencies")
+	default:
+		writeJSON(w, 201, map[string]any{"ok": true})
+	}
 }
 
-func handleCancel(db *sql.DB) http.HandlerFunc {
+func handleRemoveDependency(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		g, err := getGoal(db, id)
+		g, err := store.GetGoal(r.Context(), id)
 		if err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
 			return
 		}
 		if err != nil {
 			writeErr(w, 500, "failed to get goal")
 			return
 		}
-		if isTerminal(g.Status) {
-			writeErr(w, 409, "goal is already "+g.Status)
+		if !dependencyAllowedStatuses[g.Status] {
+			writeErr(w, 409, "cannot modify dependencies when goal is "+g.Status)
 			return
 		}
-		if err := updateGoalStatus(db, id, g.Status, "cancelled"); err != nil {
-			writeErr(w, 500, "failed to update status")
+		depIDStr := r.PathValue("dep_id")
+		depID, err := strconv.ParseInt(depIDStr, 10, 64)
+		if err != nil {
+			writeErr(w, 400, "invalid dep_id")
+			return
+		}
+		if err := store.RemoveDependency(r.Context(), id, depID); err == sql.ErrNoRows {
+			writeErr(w, 404, "dependency not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to remove dependency")
 			return
 		}
 		writeJSON(w, 200, map[string]any{"ok": true})
 	}
 }
 
-func handleCreateComment(db *sql.DB) http.HandlerFunc {
+func handleListDependencies(store Store) http.HandlerFunc {
+	return handleListLinked(store, false)
+}
+
+func handleListDependents(store Store) http.HandlerFunc {
+	return handleListLinked(store, true)
+}
+
+// handleListLinked lists the goals a goal depends on (or, with dependents,
+// the goals depending on it) as ids, or with ?expand=status as id and status
+// pairs. ?page and ?per_page paginate as on GET /goals. For dependencies,
+// ?unmet_only=true answers "what is blocking me": only the unmet ones, always
+// with their statuses.
+func handleListLinked(store Store, dependents bool) http.HandlerFunc {
+	what := "dependencies"
+	if dependents {
+		what = "dependents"
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		// Verify goal exists
-		if _, err := getGoal(db, id); err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
+		expand := r.URL.Query().Get("expand")
+		if expand != "" && expand != "status" {
+			writeErr(w, 400, "expand must be status")
 			return
-		} else if err != nil {
-			writeErr(w, 500, "failed to get goal")
+		}
+		unmetOnly := r.URL.Query().Get("unmet_only") == "true"
+		if unmetOnly && dependents {
+			writeErr(w, 400, "unmet_only only applies to dependencies")
 			return
 		}
-		var req struct {
-			Body string `json:"body"`
+		if unmetOnly {
+			expand = "status"
 		}
-		if err := readJSON(r, &req); err != nil {
-			writeErr(w, 400, "invalid JSON")
+		page, perPage, ok := readPage(w, r)
+		if !ok {
 			return
 		}
-		if req.Body == "" {
-			writeErr(w, 400, "body is required")
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to get goal")
 			return
 		}
-		cid, err := createComment(db, id, req.Body)
+		goals, total, err := store.ListLinkedGoals(r.Context(), id, dependents, unmetOnly, perPage, (page-1)*perPage)
 		if err != nil {
-			writeErr(w, 500, "failed to create comment")
+			writeErr(w, 500, "failed to list "+what)
 			return
 		}
-		writeJSON(w, 201, map[string]any{"ok": true, "id": cid, "goal_id": id})
+
+		var items any
+		if expand == "status" {
+			if goals == nil {
+				goals = []LinkedGoal{}
+			}
+			items = goals
+		} else {
+			ids := []int64{}
+			for _, g := range goals {
+				ids = append(ids, g.ID)
+			}
+			items = ids
+		}
+		if page > 0 {
+			writeJSON(w, 200, map[string]any{
+				"ok":       true,
+				"items":    items,
+				"page":     page,
+				"per_page": perPage,
+				"total":    total,
+			})
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "items": items})
+	}
+}
+
+// writeUpdateGoalErr reports a failed goal edit, as 404 or 409 when the
+// new parent is missing or would create a cycle, and 409 when the goal's
+// status changed under the edit.
+func writeUpdateGoalErr(w http.ResponseWriter, err error) {
+	var notEditable *notEditableError
+	switch {
+	case errors.As(err, &notEditable):
+		writeErr(w, 409, notEditable.Error())
+	case errors.Is(err, errParentNotFound):
+		writeErrCode(w, 404, codeGoalNotFound, err.Error())
+	case errors.Is(err, errParentCycle):
+		writeErr(w, 409, err.Error())
+	default:
+		writeErr(w, 500, "failed to update goal")
 	}
 }
 
-func handleListComments(db *sql.DB) http.HandlerFunc {
+// handleListChildren lists a goal's subtasks along with progress, the
+// fraction of them that are done (null when there are none).
+func handleListChildren(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		comments, err := listComments(db, id)
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
+			return
+		} else if err != nil {
+			writeErr(w, 500, "failed to get goal")
+			return
+		}
+		children, err := store.ListChildren(r.Context(), id)
 		if err != nil {
-			writeErr(w, 500, "failed to list comments")
+			writeErr(w, 500, "failed to list children")
 			return
 		}
-		if comments == nil {
-			comments = []Comment{}
+		if children == nil {
+			children = []GoalSummary{}
 		}
-		writeJSON(w, 200, map[string]any{"ok": true, "items": comments})
+		done := 0
+		for _, c := range children {
+			if c.Status == "done" {
+				done++
+			}
+		}
+		var progress *float64
+		if len(children) > 0 {
+			p := float64(done) / float64(len(children))
+			progress = &p
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "items": children, "done": done, "total": len(children), "progress": progress})
 	}
 }
 
-// dependencyAllowedStatuses are the statuses that allow adding/removing dependencies.
-var dependencyAllowedStatuses = map[string]bool{
-	"draft":  true,
-	"queued": true,
-	"stuck":  true,
+var tagPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
 }
 
-func handleAddDependency(db *sql.DB) http.HandlerFunc {
+func handleAddTag(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		g, err := getGoal(db, id)
-		if err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
 			return
-		}
-		if err != nil {
+		} else if err != nil {
 			writeErr(w, 500, "failed to get goal")
 			return
 		}
-		if !dependencyAllowedStatuses[g.Status] {
-			writeErr(w, 409, "cannot modify dependencies when goal is "+g.Status)
-			return
-		}
 		var req struct {
-			DependsOnID int64 `json:"depends_on_id"`
+			Tag string `json:"tag"`
 		}
 		if err := readJSON(r, &req); err != nil {
-			writeErr(w, 400, "invalid JSON")
-			return
-		}
-		if req.DependsOnID == 0 {
-			writeErr(w, 400, "depends_on_id is required")
+			writeBodyErr(w, err)
 			return
 		}
-		if req.DependsOnID == id {
-			writeErr(w, 400, "goal cannot depend on itself")
+		tag := normalizeTag(req.Tag)
+		if tag == "" {
+			writeErr(w, 400, "tag is required")
 			return
 		}
-		// Check that the dependency goal exists
-		if _, err := getGoal(db, req.DependsOnID); err == sql.ErrNoRows {
-			writeErr(w, 404, "dependency goal not found")
-			return
-		} else if err != nil {
-			writeErr(w, 500, "failed to get dependency goal")
+		if !tagPattern.MatchString(tag) {
+			writeErr(w, 400, "tag may only contain a-z, 0-9, and -")
 			return
 		}
-		if err := addDependency(db, id, req.DependsOnID); err != nil {
-			writeErr(w, 500, "failed to add dependency")
+		if err := store.AddTag(r.Context(), id, tag); err != nil {
+			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				writeErr(w, 409, "goal already has tag "+tag)
+				return
+			}
+			writeErr(w, 500, "failed to add tag")
 			return
 		}
-		writeJSON(w, 201, map[string]any{"ok": true})
+		writeJSON(w, 201, map[string]any{"ok": true, "tag": tag})
 	}
 }
 
-func handleRemoveDependency(db *sql.DB) http.HandlerFunc {
+func handleRemoveTag(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		g, err := getGoal(db, id)
-		if err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
 			return
-		}
-		if err != nil {
+		} else if err != nil {
 			writeErr(w, 500, "failed to get goal")
 			return
 		}
-		if !dependencyAllowedStatuses[g.Status] {
-			writeErr(w, 409, "cannot modify dependencies when goal is "+g.Status)
-			return
-		}
-		depIDStr := r.PathValue("dep_id")
-		depID, err := strconv.ParseInt(depIDStr, 10, 64)
-		if err != nil {
-			writeErr(w, 400, "invalid dep_id")
-			return
-		}
-		if err := removeDependency(db, id, depID); err == sql.ErrNoRows {
-			writeErr(w, 404, "dependency not found")
+		if err := store.RemoveTag(r.Context(), id, normalizeTag(r.PathValue("tag"))); err == sql.ErrNoRows {
+			writeErr(w, 404, "tag not found")
 			return
 		} else if err != nil {
-			writeErr(w, 500, "failed to remove dependency")
+			writeErr(w, 500, "failed to remove tag")
 			return
 		}
 		writeJSON(w, 200, map[string]any{"ok": true})
 	}
 }
 
-func handleListDependencies(db *sql.DB) http.HandlerFunc {
+func handleListTags(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		if _, err := getGoal(db, id); err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
 			return
 		} else if err != nil {
 			writeErr(w, 500, "failed to get goal")
 			return
 		}
-		deps, err := listDependencies(db, id)
+		tags, err := store.ListTags(r.Context(), id)
 		if err != nil {
-			writeErr(w, 500, "failed to list dependencies")
+			writeErr(w, 500, "failed to list tags")
 			return
 		}
-		if deps == nil {
-			deps = []int64{}
+		if tags == nil {
+			tags = []string{}
 		}
-		writeJSON(w, 200, map[string]any{"ok": true, "items": deps})
+		writeJSON(w, 200, map[string]any{"ok": true, "items": tags})
 	}
 }
 
-func handleCreateAttachment(db *sql.DB) http.HandlerFunc {
+func handleCreateAttachment(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		if _, err := getGoal(db, id); err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
 			return
 		} else if err != nil {
 			writeErr(w, 500, "failed to get goal")
@@ -468,7 +2158,7 @@ func handleCreateAttachment(db *sql.DB) http.HandlerFunc {
 			Body string `json:"body"`
 		}
 		if err := readJSON(r, &req); err != nil {
-			writeErr(w, 400, "invalid JSON")
+			writeBodyErr(w, err)
 			return
 		}
 		if req.Name == "" {
@@ -479,7 +2169,7 @@ func handleCreateAttachment(db *sql.DB) http.HandlerFunc {
 			writeErr(w, 400, "body is required")
 			return
 		}
-		aid, err := createAttachment(db, id, req.Name, req.Body)
+		aid, err := store.CreateAttachment(r.Context(), id, req.Name, req.Body)
 		if err != nil {
 			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 				writeErr(w, 409, "attachment name already exists for this goal")
@@ -492,21 +2182,21 @@ func handleCreateAttachment(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-func handleListAttachments(db *sql.DB) http.HandlerFunc {
+func handleListAttachments(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		if _, err := getGoal(db, id); err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
 			return
 		} else if err != nil {
 			writeErr(w, 500, "failed to get goal")
 			return
 		}
-		attachments, err := listAttachments(db, id)
+		attachments, err := store.ListAttachments(r.Context(), id)
 		if err != nil {
 			writeErr(w, 500, "failed to list attachments")
 			return
@@ -518,15 +2208,15 @@ func handleListAttachments(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-func handleGetAttachment(db *sql.DB) http.HandlerFunc {
+func handleGetAttachment(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		if _, err := getGoal(db, id); err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
 			return
 		} else if err != nil {
 			writeErr(w, 500, "failed to get goal")
@@ -538,7 +2228,7 @@ func handleGetAttachment(db *sql.DB) http.HandlerFunc {
 			writeErr(w, 400, "invalid att_id")
 			return
 		}
-		a, err := getAttachment(db, attID)
+		a, err := store.GetAttachment(r.Context(), attID)
 		if err == sql.ErrNoRows {
 			writeErr(w, 404, "attachment not found")
 			return
@@ -559,15 +2249,15 @@ func handleGetAttachment(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-func handleEditAttachment(db *sql.DB) http.HandlerFunc {
+func handleEditAttachment(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		if _, err := getGoal(db, id); err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
 			return
 		} else if err != nil {
 			writeErr(w, 500, "failed to get goal")
@@ -579,7 +2269,7 @@ func handleEditAttachment(db *sql.DB) http.HandlerFunc {
 			writeErr(w, 400, "invalid att_id")
 			return
 		}
-		a, err := getAttachment(db, attID)
+		a, err := store.GetAttachment(r.Context(), attID)
 		if err == sql.ErrNoRows {
 			writeErr(w, 404, "attachment not found")
 			return
@@ -594,7 +2284,7 @@ func handleEditAttachment(db *sql.DB) http.HandlerFunc {
 			NewStr *string `json:"new_str"`
 		}
 		if err := readJSON(r, &req); err != nil {
-			writeErr(w, 400, "invalid JSON")
+			writeBodyErr(w, err)
 			return
 		}
 		var newBody string
@@ -621,7 +2311,7 @@ func handleEditAttachment(db *sql.DB) http.HandlerFunc {
 			writeErr(w, 400, "body or old_str/new_str is required")
 			return
 		}
-		if err := editAttachmentBody(db, attID, newBody); err != nil {
+		if err := store.EditAttachmentBody(r.Context(), attID, newBody); err != nil {
 			writeErr(w, 500, "failed to edit attachment")
 			return
 		}
@@ -629,15 +2319,15 @@ func handleEditAttachment(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-func handleDeleteAttachment(db *sql.DB) http.HandlerFunc {
+func handleDeleteAttachment(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		if _, err := getGoal(db, id); err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
+		if _, err := store.GetGoal(r.Context(), id); err == sql.ErrNoRows {
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
 			return
 		} else if err != nil {
 			writeErr(w, 500, "failed to get goal")
@@ -649,7 +2339,7 @@ func handleDeleteAttachment(db *sql.DB) http.HandlerFunc {
 			writeErr(w, 400, "invalid att_id")
 			return
 		}
-		if err := deleteAttachment(db, attID); err == sql.ErrNoRows {
+		if err := store.DeleteAttachment(r.Context(), attID); err == sql.ErrNoRows {
 			writeErr(w, 404, "attachment not found")
 			return
 		} else if err != nil {
@@ -660,29 +2350,184 @@ func handleDeleteAttachment(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-// transitionHandler creates a handler for simple from->to status transitions.
-func transitionHandler(db *sql.DB, from, to string) http.HandlerFunc {
+func handleGetOrgDefaults(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d, err := store.GetOrgDefaults(r.Context(), r.PathValue("org"))
+		if err == sql.ErrNoRows {
+			writeErr(w, 404, "org has no defaults")
+			return
+		}
+		if err != nil {
+			writeErr(w, 500, "failed to get org defaults")
+			return
+		}
+		writeJSON(w, 200, d)
+	}
+}
+
+// writeCreateErr reports a failed goal insert, as 429 when the org is at
+// its quota.
+func writeCreateErr(w http.ResponseWriter, err error) {
+	var quota *quotaError
+	if errors.As(err, &quota) {
+		writeErrCode(w, 429, codeQuotaExceeded, quota.Error())
+		return
+	}
+	writeErr(w, 500, "failed to create goal")
+}
+
+func handleGetOrgQuota(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org := r.PathValue("org")
+		limit, err := store.OrgQuota(r.Context(), org)
+		if err != nil {
+			writeErr(w, 500, "failed to get org quota")
+			return
+		}
+		active, err := store.CountActiveGoals(r.Context(), org)
+		if err != nil {
+			writeErr(w, 500, "failed to count active goals")
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "org": org, "max_active": limit, "active": active})
+	}
+}
+
+// handleSetOrgQuota overrides RALPH_MAX_ACTIVE_PER_ORG for one org; a null
+// max_active removes the override.
+func handleSetOrgQuota(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org := r.PathValue("org")
+		if !githubNamePattern.MatchString(org) {
+			writeErr(w, 400, "org may only contain letters, digits, '.', '_' and '-'")
+			return
+		}
+		var req struct {
+			MaxActive *int `json:"max_active"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			writeBodyErr(w, err)
+			return
+		}
+		if req.MaxActive != nil && *req.MaxActive < 0 {
+			writeErr(w, 400, "max_active must be 0 (no limit) or more")
+			return
+		}
+		if err := store.SetOrgQuota(r.Context(), org, req.MaxActive); err != nil {
+			writeErr(w, 500, "failed to set org quota")
+			return
+		}
+		limit, err := store.OrgQuota(r.Context(), org)
+		if err != nil {
+			writeErr(w, 500, "failed to get org quota")
+			return
+		}
+		writeJSON(w, 200, map[string]any{"ok": true, "org": org, "max_active": limit})
+	}
+}
+
+// handleSetOrgDefaults replaces an org's defaults; an omitted or null field
+// means the org has no default for it.
+func handleSetOrgDefaults(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org := r.PathValue("org")
+		if !githubNamePattern.MatchString(org) {
+			writeErr(w, 400, "org may only contain letters, digits, '.', '_' and '-'")
+			return
+		}
+		var req struct {
+			Model     *string `json:"model"`
+			Reasoning *string `json:"reasoning"`
+		}
+		if err := readJSON(r, &req); err != nil {
+			writeBodyErr(w, err)
+			return
+		}
+		if req.Model != nil && !validModels[*req.Model] {
+			writeErr(w, 400, "model must be one of: haiku, sonnet, opus")
+			return
+		}
+		if req.Reasoning != nil && !validReasoning[*req.Reasoning] {
+			writeErr(w, 400, "reasoning must be one of: none, low, med, high")
+			return
+		}
+		if err := store.SetOrgDefaults(r.Context(), org, req.Model, req.Reasoning); err != nil {
+			writeErr(w, 500, "failed to set org defaults")
+			return
+		}
+		d, err := store.GetOrgDefaults(r.Context(), org)
+		if err != nil {
+			writeErr(w, 500, "failed to get org defaults")
+			return
+		}
+		writeJSON(w, 200, d)
+	}
+}
+
+// transitionBody is the optional JSON body of the status transition
+// endpoints. Note is stored on the recorded transition; Reason only applies
+// when marking a goal stuck.
+type transitionBody struct {
+	Note   *string `json:"note"`
+	Reason *string `json:"reason"`
+}
+
+// readTransitionBody decodes the optional body of a transition to status
+// to. On a bad body it writes a 400 and returns false.
+func readTransitionBody(w http.ResponseWriter, r *http.Request, to string) (transitionBody, bool) {
+	var req transitionBody
+	if err := readJSON(r, &req); err != nil && err != io.EOF {
+		writeBodyErr(w, err)
+		return req, false
+	}
+	if req.Reason != nil && to != "stuck" {
+		writeErr(w, 400, "reason is only accepted when marking a goal stuck")
+		return req, false
+	}
+	return req, true
+}
+
+// transitionHandler creates a handler that moves a goal to the given status
+// when validTransitions allows it from the goal's current status.
+func transitionHandler(store Store, to string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, err := goalIDFromRequest(r)
 		if err != nil {
 			writeErr(w, 400, "invalid goal id")
 			return
 		}
-		g, err := getGoal(db, id)
+		g, err := store.GetGoal(r.Context(), id)
 		if err == sql.ErrNoRows {
-			writeErr(w, 404, "goal not found")
+			writeErrCode(w, 404, codeGoalNotFound, "goal not found")
 			return
 		}
 		if err != nil {
 			writeErr(w, 500, "failed to get goal")
 			return
 		}
-		if g.Status != from {
-			writeErr(w, 409, "cannot transition from "+g.Status+" to "+to)
+		version, ok := checkIfMatch(w, r, g)
+		if !ok {
+			return
+		}
+		req, ok := readTransitionBody(w, r, to)
+		if !ok {
+			return
+		}
+		if !canTransition(g.Status, to) {
+			writeErrCode(w, 409, codeInvalidTransition, transitionError(g.Status, to))
+			return
+		}
+		if g.Status == "stuck" && to == "queued" {
+			writeRequeue(w, r, store, g, version, req.Note)
 			return
 		}
-		if err := updateGoalStatus(db, id, from, to); err != nil {
-			writeErr(w, 500, "failed to update status")
+		if to == "stuck" {
+			err = store.MarkGoalStuck(r.Context(), id, g.Status, version, req.Reason, req.Note)
+		} else {
+			err = store.TransitionGoal(r.Context(), id, g.Status, to, version, req.Note)
+		}
+		if err != nil {
+			writeStatusUpdateErr(w, err)
 			return
 		}
 		writeJSON(w, 200, map[string]any{"ok": true})
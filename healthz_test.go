@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestHealthz(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	t.Run("live db returns 200", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		if !resp["ok"].(bool) {
+			t.Fatal("expected ok=true")
+		}
+	})
+
+	t.Run("closed db returns 503", func(t *testing.T) {
+		closed, err := openDB(filepath.Join(tmpDir, "closed.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		closed.Close()
+
+		closedMux := http.NewServeMux()
+		registerRoutes(closedMux, newSQLiteStore(closed))
+
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		w := httptest.NewRecorder()
+		closedMux.ServeHTTP(w, req)
+
+		if w.Code != 503 {
+			t.Fatalf("expected 503, got %d", w.Code)
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		if resp["error"] != "db unavailable" {
+			t.Fatalf("expected db unavailable error, got %v", resp["error"])
+		}
+	})
+
+	t.Run("skips CORS headers", func(t *testing.T) {
+		lg := &requestLogger{corsOrigins: []string{"http://example.test"}}
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		req.Header.Set("Origin", "http://example.test")
+		w := httptest.NewRecorder()
+		lg.wrap(mux).ServeHTTP(w, req)
+
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Fatalf("expected no CORS header, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestGoalHierarchy(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w, resp
+	}
+	create := func(parentID int64) int64 {
+		payload := map[string]any{"org": "org", "repo": "repo", "title": "T", "body": "B"}
+		if parentID != 0 {
+			payload["parent_id"] = parentID
+		}
+		body, _ := json.Marshal(payload)
+		w, resp := do("POST", "/goals", string(body))
+		if w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %v", w.Code, resp)
+		}
+		return int64(resp["id"].(float64))
+	}
+	path := func(id int64) string { return "/goals/" + strconv.FormatInt(id, 10) }
+
+	ctx := context.Background()
+
+	t.Run("children and progress", func(t *testing.T) {
+		epic := create(0)
+		var children []int64
+		for range 3 {
+			children = append(children, create(epic))
+		}
+		for _, id := range children[:2] {
+			for _, step := range [][2]string{{"draft", "queued"}, {"queued", "running"}, {"running", "done"}} {
+				if err := updateGoalStatus(ctx, db, id, step[0], step[1]); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		w, resp := do("GET", path(epic)+"/children", "")
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %v", w.Code, resp)
+		}
+		items := resp["items"].([]any)
+		if len(items) != 3 {
+			t.Fatalf("expected 3 children, got %d", len(items))
+		}
+		for i, item := range items {
+			if int64(item.(map[string]any)["id"].(float64)) != children[i] {
+				t.Fatalf("expected children %v in order, got %v", children, items)
+			}
+		}
+		if resp["done"] != float64(2) || resp["total"] != float64(3) {
+			t.Fatalf("expected 2 of 3 done, got %v of %v", resp["done"], resp["total"])
+		}
+		if p := resp["progress"].(float64); p < 0.666 || p > 0.667 {
+			t.Fatalf("expected progress 2/3, got %v", p)
+		}
+
+		_, child := do("GET", path(children[0]), "")
+		if child["parent_id"] != float64(epic) {
+			t.Fatalf("expected parent_id %d, got %v", epic, child["parent_id"])
+		}
+	})
+
+	t.Run("no children", func(t *testing.T) {
+		w, resp := do("GET", path(create(0))+"/children", "")
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if len(resp["items"].([]any)) != 0 || resp["progress"] != nil {
+			t.Fatalf("expected no items and null progress, got %v", resp)
+		}
+	})
+
+	t.Run("missing parent rejected", func(t *testing.T) {
+		if w, _ := do("POST", "/goals", `{"org": "org", "repo": "repo", "title": "T", "body": "B", "parent_id": 9999}`); w.Code != 404 {
+			t.Fatalf("expected 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("own parent rejected", func(t *testing.T) {
+		id := create(0)
+		if w, _ := do("PATCH", path(id), `{"parent_id": `+strconv.FormatInt(id, 10)+`}`); w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("cycle rejected", func(t *testing.T) {
+		a := create(0)
+		b := create(a)
+		c := create(b)
+		if w, _ := do("PATCH", path(a), `{"parent_id": `+strconv.FormatInt(c, 10)+`}`); w.Code != 409 {
+			t.Fatalf("expected 409, got %d", w.Code)
+		}
+		parent := &c
+		if err := updateGoal(ctx, db, a, GoalUpdate{ParentID: &parent}); !errors.Is(err, errParentCycle) {
+			t.Fatalf("expected errParentCycle, got %v", err)
+		}
+		if g, _ := getGoal(ctx, db, a); g.ParentID != nil {
+			t.Fatalf("expected A to stay a root, got parent %d", *g.ParentID)
+		}
+	})
+
+	t.Run("reparent to missing goal rejected", func(t *testing.T) {
+		id := create(0)
+		if w, _ := do("PATCH", path(id), `{"parent_id": 9999}`); w.Code != 404 {
+			t.Fatalf("expected 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("reparent and clear", func(t *testing.T) {
+		a, b := create(0), create(0)
+		child := create(a)
+		if w, resp := do("PATCH", path(child), `{"parent_id": `+strconv.FormatInt(b, 10)+`}`); w.Code != 200 || resp["parent_id"] != float64(b) {
+			t.Fatalf("expected reparent to %d, got %d %v", b, w.Code, resp["parent_id"])
+		}
+		if w, resp := do("PATCH", path(child), `{"parent_id": null}`); w.Code != 200 || resp["parent_id"] != nil {
+			t.Fatalf("expected parent cleared, got %d %v", w.Code, resp["parent_id"])
+		}
+	})
+
+	t.Run("deleting parent orphans children", func(t *testing.T) {
+		parent := create(0)
+		child := create(parent)
+		if w, _ := do("DELETE", path(parent), ""); w.Code != 200 {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		g, err := getGoal(ctx, db, child)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.ParentID != nil {
+			t.Fatalf("expected parent_id cleared, got %d", *g.ParentID)
+		}
+	})
+}
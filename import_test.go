@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	tmpDir := t.TempDir()
+	open := func(name string) (*sql.DB, *http.ServeMux) {
+		db, err := openDB(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		mux := http.NewServeMux()
+		registerRoutes(mux, newSQLiteStore(db))
+		return db, mux
+	}
+	src, srcMux := open("src.db")
+
+	adminAPIKey = "secret"
+	defer func() { adminAPIKey = "" }()
+
+	ctx := context.Background()
+	model := "opus"
+	a, err := createGoal(ctx, src, "org", "repo", "A", "Body A", &model, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := createGoal(ctx, src, "org", "repo", "B", "Body B", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addDependency(ctx, src, b, a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createComment(ctx, src, a, "a comment"); err != nil {
+		t.Fatal(err)
+	}
+	if err := addTag(ctx, src, a, "infra"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createAttachment(ctx, src, a, "plan.md", "the plan"); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateGoalStatus(ctx, src, a, "draft", "queued"); err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(mux *http.ServeMux, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	export := do(srcMux, "GET", "/admin/export", "").Body.String()
+
+	decode := func(w *httptest.ResponseRecorder) ImportResult {
+		t.Helper()
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var r ImportResult
+		if err := json.Unmarshal(w.Body.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		dst, dstMux := open("round.db")
+		r := decode(do(dstMux, "POST", "/admin/import", export))
+		if len(r.Imported) != 2 || r.Imported[0] != a || r.Imported[1] != b {
+			t.Fatalf("expected goals %d and %d imported, got %+v", a, b, r)
+		}
+
+		var want, got []GoalExport
+		for _, e := range []struct {
+			body string
+			into *[]GoalExport
+		}{{export, &want}, {do(dstMux, "GET", "/admin/export", "").Body.String(), &got}} {
+			dec := json.NewDecoder(strings.NewReader(e.body))
+			for dec.More() {
+				var g GoalExport
+				if err := dec.Decode(&g); err != nil {
+					t.Fatal(err)
+				}
+				*e.into = append(*e.into, g)
+			}
+		}
+		for i := range want {
+			wg, _ := json.Marshal(want[i].Goal)
+			gg, _ := json.Marshal(got[i].Goal)
+			if string(wg) != string(gg) {
+				t.Fatalf("goal differs after import:\n%s\n%s", wg, gg)
+			}
+			if len(got[i].Comments) != len(want[i].Comments) || len(got[i].Transitions) != len(want[i].Transitions) ||
+				len(got[i].Tags) != len(want[i].Tags) || len(got[i].Dependencies) != len(want[i].Dependencies) ||
+				len(got[i].Attachments) != len(want[i].Attachments) {
+				t.Fatalf("goal %d attachments differ:\n%+v\n%+v", want[i].Goal.ID, want[i], got[i])
+			}
+		}
+		if deps, _ := listDependencies(ctx, dst, b); len(deps) != 1 || deps[0] != a {
+			t.Fatalf("expected B to depend on A, got %v", deps)
+		}
+		if atts, _ := listAttachmentBodies(ctx, dst, a); len(atts) != 1 || atts[0].Name != "plan.md" || atts[0].Body != "the plan" {
+			t.Fatalf("expected A's attachment, got %+v", atts)
+		}
+	})
+
+	t.Run("new ids remap dependencies", func(t *testing.T) {
+		dst, dstMux := open("remap.db")
+		if _, err := createGoal(ctx, dst, "org", "repo", "existing", "Body", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		r := decode(do(dstMux, "POST", "/admin/import?preserve_ids=false", export))
+		if len(r.Imported) != 2 || r.Imported[0] == a {
+			t.Fatalf("expected new ids, got %+v", r)
+		}
+		deps, err := listDependencies(ctx, dst, r.Imported[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deps) != 1 || deps[0] != r.Imported[0] {
+			t.Fatalf("expected dependency on %d, got %v", r.Imported[0], deps)
+		}
+	})
+
+	t.Run("conflict modes", func(t *testing.T) {
+		dst, dstMux := open("modes.db")
+		local, err := createGoal(ctx, dst, "org", "repo", "local", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := createAttachment(ctx, dst, local, "local.md", "stale"); err != nil {
+			t.Fatal(err)
+		}
+
+		if w := do(dstMux, "POST", "/admin/import", export); w.Code != 409 {
+			t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+		}
+		var count int
+		dst.QueryRow(`SELECT COUNT(*) FROM goals`).Scan(&count)
+		if count != 1 {
+			t.Fatalf("expected failed import to roll back, got %d goals", count)
+		}
+
+		r := decode(do(dstMux, "POST", "/admin/import?mode=skip", export))
+		if len(r.Skipped) != 1 || r.Skipped[0] != a || len(r.Imported) != 1 || r.Imported[0] != b {
+			t.Fatalf("expected A skipped and B imported, got %+v", r)
+		}
+		if g, _ := getGoal(ctx, dst, a); g.Title != "local" {
+			t.Fatalf("expected skipped goal untouched, got %q", g.Title)
+		}
+
+		r = decode(do(dstMux, "POST", "/admin/import?mode=replace", export))
+		if len(r.Imported) != 2 {
+			t.Fatalf("expected both goals replaced, got %+v", r)
+		}
+		g, err := getGoal(ctx, dst, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Title != "A" || g.Status != "queued" {
+			t.Fatalf("expected A replaced, got %+v", g)
+		}
+		if atts, _ := listAttachmentBodies(ctx, dst, a); len(atts) != 1 || atts[0].Name != "plan.md" {
+			t.Fatalf("expected only the imported attachment, got %+v", atts)
+		}
+		if deps, _ := listDependencies(ctx, dst, b); len(deps) != 1 || deps[0] != a {
+			t.Fatalf("expected B to still depend on A, got %v", deps)
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		_, dstMux := open("invalid.db")
+		// line builds an otherwise valid export line for goal id, with extra
+		// goal fields and extra top-level fields spliced in
+		line := func(id int, goalExtra, extra string) string {
+			return fmt.Sprintf(`{"goal":{"id":%d,"org":"o","repo":"r","title":"t","body":"b","status":"draft","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"%s}%s}`, id, goalExtra, extra)
+		}
+		for _, tc := range []struct{ name, query, body, want string }{
+			{"bad mode", "?mode=merge", export, "mode"},
+			{"bad json", "", "{not json}\n", "invalid JSON"},
+			{"bad status", "", line(1, `,"status":"bogus"`, ""), "invalid status"},
+			{"missing dependency", "", line(1, "", `,"dependencies":[99]`), "missing goal 99"},
+			{"cycle", "", line(1, "", `,"dependencies":[2]`) + "\n" + line(2, "", `,"dependencies":[1]`), "cycle"},
+			{"bad model", "", line(1, `,"model":"gpt"`, ""), "invalid model"},
+			{"bad reasoning", "", line(1, `,"reasoning":"max"`, ""), "invalid reasoning"},
+			{"bad priority", "", line(1, `,"priority":101`, ""), "priority"},
+			{"duplicate attachment", "", line(1, "", `,"attachments":[{"name":"a","body":"x","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"},{"name":"a","body":"y","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}]`), "attachment names"},
+			{"bad goal timestamp", "", line(1, `,"created_at":"yesterday"`, ""), "invalid created_at"},
+			{"fractional timestamp", "", line(1, `,"updated_at":"2026-01-01T00:00:00.5Z"`, ""), "invalid updated_at"},
+			{"bad comment timestamp", "", line(1, "", `,"comments":[{"body":"c","created_at":"2026-01-01"}]`), "comment created_at"},
+			{"bad transition timestamp", "", line(1, "", `,"transitions":[{"to_status":"draft","created_at":""}]`), "transition created_at"},
+			{"metadata array", "", line(1, `,"metadata":[1,2]`, ""), "metadata must be a JSON object"},
+			{"metadata scalar", "", line(1, `,"metadata":"x"`, ""), "metadata must be a JSON object"},
+			{"metadata too large", "", line(1, `,"metadata":{"k":"`+strings.Repeat("x", maxMetadataBytes)+`"}`, ""), "metadata exceeds"},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				w := do(dstMux, "POST", "/admin/import"+tc.query, tc.body)
+				if w.Code != 400 {
+					t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+				}
+				if !strings.Contains(w.Body.String(), tc.want) {
+					t.Fatalf("expected error about %q, got %s", tc.want, w.Body.String())
+				}
+			})
+		}
+	})
+
+	t.Run("oversized body rejected", func(t *testing.T) {
+		_, dstMux := open("large.db")
+		old := maxImportBytes
+		maxImportBytes = int64(len(export) - 1)
+		defer func() { maxImportBytes = old }()
+
+		if w := do(dstMux, "POST", "/admin/import", export); w.Code != 413 {
+			t.Fatalf("expected 413, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("requires admin", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/admin/import", strings.NewReader(export))
+		w := httptest.NewRecorder()
+		srcMux.ServeHTTP(w, req)
+		if w.Code != 401 {
+			t.Fatalf("expected 401, got %d", w.Code)
+		}
+	})
+}
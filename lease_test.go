@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLeaseSweep(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	claimOne := func() *Goal {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+		g, err := claimNextGoal(context.Background(), db, "", "", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.LeaseExpiresAt == nil {
+			t.Fatal("expected claim to set a lease")
+		}
+		return g
+	}
+
+	alive := claimOne()
+	stale := claimOne()
+
+	// Both leases lapse, but the live worker heartbeats before the sweep
+	past := time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)
+	if _, err := db.Exec(`UPDATE goals SET lease_expires_at = ?`, past); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("PATCH", "/goals/"+strconv.FormatInt(alive.ID, 10)+"/heartbeat", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("expected 200 from heartbeat, got %d: %s", w.Code, w.Body.String())
+	}
+
+	ids, stuck, err := requeueExpiredLeases(context.Background(), db, time.Now(), maxRetries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != stale.ID || len(stuck) != 0 {
+		t.Fatalf("expected only goal %d requeued, got %v (stuck %v)", stale.ID, ids, stuck)
+	}
+
+	t.Run("heartbeated goal survives", func(t *testing.T) {
+		g, err := getGoal(context.Background(), db, alive.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Status != "running" {
+			t.Fatalf("expected running, got %s", g.Status)
+		}
+	})
+
+	t.Run("stale goal requeued with a retry", func(t *testing.T) {
+		g, err := getGoal(context.Background(), db, stale.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Status != "queued" {
+			t.Fatalf("expected queued, got %s", g.Status)
+		}
+		if g.Retries != 1 {
+			t.Fatalf("expected retries=1, got %d", g.Retries)
+		}
+		if g.LeaseExpiresAt != nil {
+			t.Fatalf("expected lease cleared, got %s", *g.LeaseExpiresAt)
+		}
+	})
+
+	t.Run("goal at the retry limit marked stuck", func(t *testing.T) {
+		// A repo of its own, so the requeued goal above isn't claimed instead
+		id, err := createGoal(context.Background(), db, "org", "spent", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+		spent, err := claimNextGoal(context.Background(), db, "org", "spent", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`UPDATE goals SET retries = ?, lease_expires_at = ? WHERE id = ?`, maxRetries, past, spent.ID); err != nil {
+			t.Fatal(err)
+		}
+		requeued, stuck, err := requeueExpiredLeases(context.Background(), db, time.Now(), maxRetries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(requeued) != 0 || len(stuck) != 1 || stuck[0] != spent.ID {
+			t.Fatalf("expected only goal %d stuck, got requeued %v stuck %v", spent.ID, requeued, stuck)
+		}
+		g, err := getGoal(context.Background(), db, spent.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Status != "stuck" || g.StuckReason == nil || *g.StuckReason != leaseRetriesReason {
+			t.Fatalf("expected stuck with reason, got %s %v", g.Status, g.StuckReason)
+		}
+		if g.Retries != maxRetries {
+			t.Fatalf("expected retries to stay at %d, got %d", maxRetries, g.Retries)
+		}
+	})
+
+	t.Run("heartbeat rejected when not running", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/goals/"+strconv.FormatInt(stale.ID, 10)+"/heartbeat", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 409 {
+			t.Fatalf("expected 409, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogRotation(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "ralph-plans.jsonl")
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg := &requestLogger{f: f, path: logPath, maxBytes: 1024}
+	defer func() { lg.f.Close() }()
+
+	h := lg.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, 200, map[string]any{"ok": true})
+	}))
+	for i := 0; i < 100; i++ {
+		req := httptest.NewRequest("POST", "/goals", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("generations kept", func(t *testing.T) {
+		for i := 1; i <= logGenerations; i++ {
+			info, err := os.Stat(fmt.Sprintf("%s.%d", logPath, i))
+			if err != nil {
+				t.Fatalf("expected rotated file %d: %v", i, err)
+			}
+			if info.Size() > lg.maxBytes {
+				t.Fatalf("rotated file %d is %d bytes, over the %d limit", i, info.Size(), lg.maxBytes)
+			}
+		}
+		if _, err := os.Stat(fmt.Sprintf("%s.%d", logPath, logGenerations+1)); !os.IsNotExist(err) {
+			t.Fatalf("expected no generation past %d", logGenerations)
+		}
+	})
+
+	t.Run("active file reset", func(t *testing.T) {
+		info, err := os.Stat(logPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() == 0 || info.Size() > lg.maxBytes {
+			t.Fatalf("expected active file between 1 and %d bytes, got %d", lg.maxBytes, info.Size())
+		}
+		if info.Size() != lg.size {
+			t.Fatalf("expected tracked size %d to match file size %d", lg.size, info.Size())
+		}
+	})
+}
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -12,23 +20,46 @@ import (
 )
 
 type requestLogger struct {
-	f          *os.File
-	mu         sync.Mutex
-	corsOrigin string
+	f  *os.File
+	mu sync.Mutex
+	// corsOrigins lists the origins allowed to call the API from a browser.
+	corsOrigins []string
+	// corsMethods is the Access-Control-Allow-Methods value, derived from
+	// the registered routes.
+	corsMethods string
+	// logGets enables logging of GET/HEAD requests, of which only
+	// getSampleRate (0 to 1) are written to keep read traffic from
+	// flooding the log.
+	logGets       bool
+	getSampleRate float64
+	// When maxBytes > 0, the file at path is rotated once it would grow
+	// past maxBytes. size tracks its current length.
+	path     string
+	maxBytes int64
+	size     int64
 }
 
+// logGenerations is how many rotated files (path.1 .. path.N) are kept.
+const logGenerations = 3
+
 type logEntry struct {
 	Time       string `json:"time"`
+	RequestID  string `json:"request_id"`
 	Method     string `json:"method"`
 	Path       string `json:"path"`
 	Status     int    `json:"status"`
 	GoalID     int64  `json:"goal_id,omitempty"`
 	DurationMs int64  `json:"duration_ms"`
+	BytesIn    int64  `json:"bytes_in"`
+	BytesOut   int64  `json:"bytes_out"`
+	ClientIP   string `json:"client_ip"`
+	UserAgent  string `json:"user_agent,omitempty"`
 }
 
 type statusWriter struct {
 	http.ResponseWriter
 	status int
+	bytes  int64
 }
 
 func (w *statusWriter) WriteHeader(code int) {
@@ -36,31 +67,109 @@ func (w *statusWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+func (w *statusWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush passes through to the underlying writer, so streamed responses such
+// as exports reach the client as they are written.
+func (w *statusWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// countingReader tallies bytes read from a request body, which unlike
+// ContentLength is accurate for chunked uploads.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// maxRequestIDLen caps an incoming X-Request-Id so clients can't bloat the log.
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// requestID returns the ID the logging middleware assigned to the request
+// carrying ctx, or "" outside one. Include it in any log line written while
+// handling a request so it can be matched to the access log.
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	crand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 func (rl *requestLogger) wrap(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", rl.corsOrigin)
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		// Health checks bypass CORS and logging entirely
+		if r.URL.Path == "/healthz" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); origin != "" && slices.Contains(rl.corsOrigins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", rl.corsMethods)
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-Id")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Request-Id")
+		}
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(204)
 			return
 		}
 
+		id := r.Header.Get("X-Request-Id")
+		if id == "" || len(id) > maxRequestIDLen {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-Id", id)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+
 		start := time.Now()
 		sw := &statusWriter{ResponseWriter: w, status: 200}
+		body := &countingReader{ReadCloser: r.Body}
+		r.Body = body
 		next.ServeHTTP(sw, r)
 
 		if r.Method == http.MethodGet || r.Method == http.MethodHead {
-			return
+			if !rl.logGets || rand.Float64() >= rl.getSampleRate {
+				return
+			}
 		}
 
 		entry := logEntry{
 			Time:       start.UTC().Format(time.RFC3339),
+			RequestID:  id,
 			Method:     r.Method,
 			Path:       r.URL.Path,
 			Status:     sw.status,
 			DurationMs: time.Since(start).Milliseconds(),
+			BytesIn:    body.n,
+			BytesOut:   sw.bytes,
+			ClientIP:   r.RemoteAddr,
+			UserAgent:  r.UserAgent(),
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			entry.ClientIP = host
 		}
 
 		// Extract goal_id from path: /goals/{id}/...
@@ -74,8 +183,35 @@ func (rl *requestLogger) wrap(next http.Handler) http.Handler {
 		rl.mu.Lock()
 		defer rl.mu.Unlock()
 		data, err := json.Marshal(entry)
-		if err == nil {
-			fmt.Fprintf(rl.f, "%s\n", data)
+		if err != nil {
+			return
 		}
+		if rl.maxBytes > 0 && rl.size > 0 && rl.size+int64(len(data))+1 > rl.maxBytes {
+			if err := rl.rotate(); err != nil {
+				log.Printf("log rotation failed: %v", err)
+			}
+		}
+		n, _ := fmt.Fprintf(rl.f, "%s\n", data)
+		rl.size += int64(n)
 	})
 }
+
+// rotate shifts path.1 .. path.N-1 up one generation, moves the active file
+// to path.1, and reopens path empty. The caller must hold rl.mu. The old file
+// stays open until the new one is, so a failure leaves logging intact.
+func (rl *requestLogger) rotate() error {
+	for i := logGenerations - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", rl.path, i), fmt.Sprintf("%s.%d", rl.path, i+1))
+	}
+	if err := os.Rename(rl.path, rl.path+".1"); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(rl.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	rl.f.Close()
+	rl.f = f
+	rl.size = 0
+	return nil
+}
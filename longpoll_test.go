@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLongPollChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	ctx := context.Background()
+	id, err := createGoal(ctx, db, "org", "repo", "Goal", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cursor int64
+	if err := db.QueryRow(`SELECT MAX(id) FROM goal_transitions`).Scan(&cursor); err != nil {
+		t.Fatal(err)
+	}
+	since := strconv.FormatInt(cursor, 10)
+
+	type feed struct {
+		Items  []Change `json:"items"`
+		Cursor int64    `json:"cursor"`
+	}
+	poll := func(ctx context.Context, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/goals/changes/longpoll"+query, nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("transition wakes waiter", func(t *testing.T) {
+		done := make(chan *httptest.ResponseRecorder)
+		start := time.Now()
+		go func() { done <- poll(ctx, "?since="+since+"&timeout=10s") }()
+
+		time.Sleep(50 * time.Millisecond)
+		if err := updateGoalStatus(ctx, db, id, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+
+		var w *httptest.ResponseRecorder
+		select {
+		case w = <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("long-poll was not woken by the transition")
+		}
+		if time.Since(start) >= 10*time.Second {
+			t.Fatal("long-poll waited for its timeout")
+		}
+		var f feed
+		json.NewDecoder(w.Body).Decode(&f)
+		if len(f.Items) != 1 || f.Items[0].ToStatus != "queued" || f.Cursor != f.Items[0].ID {
+			t.Fatalf("expected the queued transition, got %+v", f)
+		}
+		since = strconv.FormatInt(f.Cursor, 10)
+	})
+
+	t.Run("waiters are woken only after commit", func(t *testing.T) {
+		wake := transitionsChanged.wait()
+		err := inTxNotify(ctx, db, func(tx *sql.Tx) error {
+			if err := recordTransition(ctx, tx, id, "queued", "running", timestamp(time.Now()), nil); err != nil {
+				return err
+			}
+			select {
+			case <-wake:
+				t.Error("waiter woken before the transaction committed")
+			default:
+			}
+			return errors.New("rolled back")
+		})
+		if err == nil {
+			t.Fatal("expected the transaction to fail")
+		}
+		select {
+		case <-wake:
+			t.Fatal("waiter woken by a rolled-back transaction")
+		default:
+		}
+	})
+
+	t.Run("returns immediately when behind", func(t *testing.T) {
+		w := poll(ctx, "?since=0&timeout=10s")
+		var f feed
+		json.NewDecoder(w.Body).Decode(&f)
+		if len(f.Items) != 2 {
+			t.Fatalf("expected both existing transitions, got %d", len(f.Items))
+		}
+	})
+
+	t.Run("timeout returns unchanged cursor", func(t *testing.T) {
+		w := poll(ctx, "?since="+since+"&timeout=50ms")
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		var f feed
+		json.NewDecoder(w.Body).Decode(&f)
+		if len(f.Items) != 0 || strconv.FormatInt(f.Cursor, 10) != since {
+			t.Fatalf("expected no items and cursor %s, got %+v", since, f)
+		}
+	})
+
+	t.Run("client disconnect ends wait", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			poll(cctx, "?since="+since+"&timeout=10s")
+			close(done)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("long-poll kept waiting after the client went away")
+		}
+	})
+
+	t.Run("bad timeout rejected", func(t *testing.T) {
+		if w := poll(ctx, "?timeout=soon"); w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestLongPollRequestTimeout(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+	handler := withRequestTimeout(mux, 50*time.Millisecond)
+
+	req := httptest.NewRequest("GET", "/goals/changes/longpoll?since=0&timeout=10s", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("expected a JSON reply, got %q", w.Body.String())
+	}
+	if items, _ := resp["items"].([]any); resp["items"] == nil || len(items) != 0 {
+		t.Fatalf("expected empty items, got %v", resp["items"])
+	}
+}
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 )
 
+// shutdownTimeout bounds how long in-flight requests get to finish on exit.
+const shutdownTimeout = 10 * time.Second
+
 func requireEnv(key string) string {
 	v := os.Getenv(key)
 	if v == "" {
@@ -17,11 +26,144 @@ func requireEnv(key string) string {
 	return v
 }
 
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("%s must be an integer", key)
+	}
+	return n
+}
+
+// serve runs srv on ln until ctx is cancelled, then shuts it down gracefully,
+// waiting up to shutdownTimeout for in-flight requests to complete.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener) error {
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// untimedPaths are exempt from the request timeout. They stream the whole
+// database, so their running time grows with it; the export routes read it
+// in batches of short queries, and a backup is a single VACUUM INTO.
+var untimedPaths = map[string]bool{
+	"/admin/backup": true,
+	"/admin/export": true,
+	"/goals.csv":    true,
+}
+
+// withRequestTimeout bounds each request's context, so queries made with it
+// are abandoned rather than holding the single DB connection indefinitely.
+func withRequestTimeout(next http.Handler, d time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if untimedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("%s must be a duration (e.g. 30s, 5m)", key)
+	}
+	return d
+}
+
+// envInterval is envDuration for ticker periods, which must be positive.
+func envInterval(key string, def time.Duration) time.Duration {
+	d := envDuration(key, def)
+	if d <= 0 {
+		log.Fatalf("%s must be greater than zero", key)
+	}
+	return d
+}
+
+func envFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Fatalf("%s must be a number", key)
+	}
+	return f
+}
+
+func envBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("%s must be true or false", key)
+	}
+	return b
+}
+
+// corsOrigins returns the comma-separated RALPH_CORS_ORIGINS list, or def
+// alone when it is unset.
+func corsOrigins(def string) []string {
+	v := os.Getenv("RALPH_CORS_ORIGINS")
+	if v == "" {
+		return []string{def}
+	}
+	var origins []string
+	for _, o := range strings.Split(v, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
+
 func main() {
 	plansHost := requireEnv("RALPH_PLANS_HOST")
 	plansPort := requireEnv("RALPH_PLANS_PORT")
 	showsHost := requireEnv("RALPH_SHOWS_HOST")
 	showsPort := requireEnv("RALPH_SHOWS_PORT")
+	maxRetries = envInt("RALPH_MAX_RETRIES", 3)
+	leaseDuration = envDuration("RALPH_LEASE_DURATION", 5*time.Minute)
+	cancelledDepsSatisfied = envBool("RALPH_CANCELLED_DEPS_SATISFIED", false)
+	allowCrossRepoDeps = envBool("RALPH_ALLOW_CROSS_REPO_DEPS", false)
+	maxActivePerOrg = envInt("RALPH_MAX_ACTIVE_PER_ORG", 0)
+	busyRetries = envInt("RALPH_BUSY_RETRIES", 3)
+	busyBackoff = envDuration("RALPH_BUSY_BACKOFF", 50*time.Millisecond)
+	dbMaxOpenConns = envInt("RALPH_DB_MAX_CONNS", 1)
+	if dbMaxOpenConns < 1 {
+		// SetMaxOpenConns treats zero or less as unlimited
+		log.Fatalf("RALPH_DB_MAX_CONNS must be at least 1")
+	}
+	dbMaxIdleConns = envInt("RALPH_DB_MAX_IDLE_CONNS", 2)
+	dbConnMaxLifetime = envDuration("RALPH_DB_CONN_MAX_LIFETIME", 0)
+	adminAPIKey = os.Getenv("RALPH_ADMIN_KEY")
+	pprofEnabled = envBool("RALPH_PPROF", false)
+	webhookURL = os.Getenv("RALPH_WEBHOOK_URL")
+	maxTitleLen = envInt("RALPH_MAX_TITLE_LEN", 500)
+	maxBodyLen = envInt("RALPH_MAX_BODY_LEN", 64<<10)
+	maxImportBytes = int64(envInt("RALPH_MAX_IMPORT_BYTES", 64<<20))
 
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -59,14 +201,55 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer logFile.Close()
 
-	lg := &requestLogger{f: logFile, corsOrigin: "http://" + showsHost + ":" + showsPort}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
+	store := newSQLiteStore(db)
 	mux := http.NewServeMux()
-	registerRoutes(mux, db)
+	methods := registerRoutes(mux, store)
+
+	lg := &requestLogger{
+		f:             logFile,
+		corsOrigins:   corsOrigins("http://" + showsHost + ":" + showsPort),
+		corsMethods:   strings.Join(methods, ", "),
+		logGets:       envBool("RALPH_LOG_GETS", false),
+		getSampleRate: envFloat("RALPH_LOG_GET_SAMPLE", 1),
+		path:          logPath,
+		maxBytes:      int64(envInt("RALPH_LOG_MAX_BYTES", 100<<20)),
+	}
+	// Rotation may swap lg.f, so close whichever file is current on exit
+	defer func() { lg.f.Close() }()
+
+	startLeaseSweeper(ctx, store, envInterval("RALPH_LEASE_SWEEP_INTERVAL", 30*time.Second))
+	if ttl := envDuration("RALPH_DRAFT_TTL", 0); ttl > 0 {
+		startDraftSweeper(ctx, store, ttl, envInterval("RALPH_DRAFT_SWEEP_INTERVAL", time.Hour))
+	}
+	if timeout := envDuration("RALPH_RUNNING_TIMEOUT", 0); timeout > 0 {
+		startRunningTimeoutSweeper(ctx, store, timeout, envInterval("RALPH_RUNNING_SWEEP_INTERVAL", time.Minute))
+	}
+	if webhookURL != "" {
+		sender := newWebhookSender(os.Getenv("RALPH_WEBHOOK_SECRET"))
+		startWebhookDispatcher(ctx, store, sender, envInterval("RALPH_WEBHOOK_INTERVAL", 2*time.Second))
+	}
 
 	addr := plansHost + ":" + plansPort
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
 	fmt.Printf("ralph-plans listening on %s\n", addr)
-	log.Fatal(http.ListenAndServe(addr, lg.wrap(mux)))
+
+	requestTimeout := envDuration("RALPH_REQUEST_TIMEOUT", 30*time.Second)
+	handler := withRequestTimeout(withRouteErrors(mux), requestTimeout)
+	// Rate limiting is off unless RALPH_RATE_RPS is set
+	if rps := envFloat("RALPH_RATE_RPS", 0); rps > 0 {
+		limiter := newRateLimiter(rps, envInt("RALPH_RATE_BURST", 20), envBool("RALPH_RATE_TRUST_PROXY", false))
+		handler = limiter.wrap(handler)
+	}
+	srv := &http.Server{Handler: lg.wrap(withGzip(handler))}
+	if err := serve(ctx, srv, ln); err != nil {
+		log.Print(err)
+	}
+	fmt.Println("ralph-plans stopped")
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoalMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp map[string]any
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	w, resp := do("POST", "/goals", `{"org":"org","repo":"repo","title":"T","body":"B","metadata":{"branch":"feat/x","ci":{"run":1}}}`)
+	if w.Code != 201 {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	path := w.Header().Get("Location")
+	if md := resp["metadata"].(map[string]any); md["branch"] != "feat/x" {
+		t.Fatalf("expected metadata on create, got %v", resp["metadata"])
+	}
+
+	t.Run("patch merges keys", func(t *testing.T) {
+		w, resp := do("PATCH", path+"/metadata", `{"sha":"abc123","ci":null,"branch":"feat/y"}`)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		md := resp["metadata"].(map[string]any)
+		if md["sha"] != "abc123" || md["branch"] != "feat/y" {
+			t.Fatalf("expected merged keys, got %v", md)
+		}
+		if _, ok := md["ci"]; ok {
+			t.Fatalf("expected null to remove ci, got %v", md)
+		}
+
+		_, resp = do("GET", path, "")
+		got := resp["metadata"].(map[string]any)
+		if len(got) != 2 || got["sha"] != "abc123" {
+			t.Fatalf("expected GET to return merged metadata, got %v", got)
+		}
+	})
+
+	t.Run("unset metadata is null", func(t *testing.T) {
+		w, resp := do("POST", "/goals", `{"org":"org","repo":"repo","title":"T","body":"B"}`)
+		if w.Code != 201 {
+			t.Fatalf("expected 201, got %d", w.Code)
+		}
+		if resp["metadata"] != nil {
+			t.Fatalf("expected null metadata, got %v", resp["metadata"])
+		}
+		w, resp = do("PATCH", w.Header().Get("Location")+"/metadata", `{"k":"v"}`)
+		if w.Code != 200 || resp["metadata"].(map[string]any)["k"] != "v" {
+			t.Fatalf("expected first patch to create metadata, got %d %v", w.Code, resp)
+		}
+	})
+
+	t.Run("non-object rejected", func(t *testing.T) {
+		for _, body := range []string{`["a"]`, `"text"`, `42`, `null`} {
+			if w, _ := do("PATCH", path+"/metadata", body); w.Code != 400 {
+				t.Fatalf("PATCH %s: expected 400, got %d", body, w.Code)
+			}
+			w, _ := do("POST", "/goals", `{"org":"org","repo":"repo","title":"T","body":"B","metadata":`+body+`}`)
+			if body == `null` {
+				continue
+			}
+			if w.Code != 400 {
+				t.Fatalf("create with %s: expected 400, got %d", body, w.Code)
+			}
+		}
+	})
+
+	t.Run("size limit enforced", func(t *testing.T) {
+		big := `{"blob":"` + strings.Repeat("x", maxMetadataBytes) + `"}`
+		w, resp := do("PATCH", path+"/metadata", big)
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+		if !strings.Contains(resp["error"].(string), "exceeds") {
+			t.Fatalf("expected size error, got %v", resp["error"])
+		}
+		if w, _ := do("POST", "/goals", `{"org":"org","repo":"repo","title":"T","body":"B","metadata":`+big+`}`); w.Code != 400 {
+			t.Fatalf("expected 400 on create, got %d", w.Code)
+		}
+	})
+
+	t.Run("missing goal returns 404", func(t *testing.T) {
+		if w, _ := do("PATCH", "/goals/9999/metadata", `{"k":"v"}`); w.Code != 404 {
+			t.Fatalf("expected 404, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRouteErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+	h := withRouteErrors(mux)
+
+	do := func(method, path string) (*httptest.ResponseRecorder, map[string]any) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		var resp map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s: expected JSON body, got %q", method, path, w.Body.String())
+		}
+		return w, resp
+	}
+
+	t.Run("unsupported method returns 405 with Allow", func(t *testing.T) {
+		w, resp := do("PUT", "/goals/1")
+		if w.Code != 405 {
+			t.Fatalf("expected 405, got %d", w.Code)
+		}
+		allow := w.Header().Get("Allow")
+		if !strings.Contains(allow, "GET") || !strings.Contains(allow, "PATCH") {
+			t.Fatalf("expected Allow to list GET and PATCH, got %q", allow)
+		}
+		if resp["ok"] != false || resp["error"] != "method not allowed" {
+			t.Fatalf("unexpected body %v", resp)
+		}
+	})
+
+	t.Run("DELETE on collection", func(t *testing.T) {
+		w, _ := do("DELETE", "/goals")
+		if w.Code != 405 || !strings.Contains(w.Header().Get("Allow"), "POST") {
+			t.Fatalf("expected 405 allowing POST, got %d %q", w.Code, w.Header().Get("Allow"))
+		}
+	})
+
+	t.Run("unknown path returns JSON 404", func(t *testing.T) {
+		w, resp := do("GET", "/nope")
+		if w.Code != 404 || resp["error"] != "not found" {
+			t.Fatalf("expected JSON 404, got %d %v", w.Code, resp)
+		}
+	})
+
+	t.Run("matched routes are untouched", func(t *testing.T) {
+		w, resp := do("GET", "/goals/9999")
+		if w.Code != 404 || resp["error"] != "goal not found" {
+			t.Fatalf("expected handler's 404, got %d %v", w.Code, resp)
+		}
+	})
+}
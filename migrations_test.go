@@ -0,0 +1,85 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestSchemaMigrations(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	versions := func(db *sql.DB) map[int]string {
+		rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations ORDER BY version`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		applied := map[int]string{}
+		for rows.Next() {
+			var v int
+			var at string
+			if err := rows.Scan(&v, &at); err != nil {
+				t.Fatal(err)
+			}
+			applied[v] = at
+		}
+		return applied
+	}
+
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := versions(db)
+	db.Close()
+
+	t.Run("fresh database records every version", func(t *testing.T) {
+		if len(first) != len(migrations) {
+			t.Fatalf("expected %d versions, got %d", len(migrations), len(first))
+		}
+		for v := 1; v <= len(migrations); v++ {
+			if _, ok := first[v]; !ok {
+				t.Fatalf("version %d not recorded", v)
+			}
+		}
+	})
+
+	t.Run("reopening is a no-op", func(t *testing.T) {
+		db, err := openDB(dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		again := versions(db)
+		if len(again) != len(first) {
+			t.Fatalf("expected %d versions, got %d", len(first), len(again))
+		}
+		for v, at := range first {
+			if again[v] != at {
+				t.Fatalf("version %d re-applied: %s -> %s", v, at, again[v])
+			}
+		}
+	})
+
+	t.Run("database from before versioning is brought up to date", func(t *testing.T) {
+		db, err := openDB(dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`DROP TABLE schema_migrations`); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+
+		db, err = openDB(dbPath)
+		if err != nil {
+			t.Fatalf("legacy database failed to migrate: %v", err)
+		}
+		defer db.Close()
+		if got := versions(db); len(got) != len(migrations) {
+			t.Fatalf("expected %d versions, got %d", len(migrations), len(got))
+		}
+	})
+}
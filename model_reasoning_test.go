@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -21,7 +22,7 @@ func TestModelReasoningFields(t *testing.T) {
 
 	// Create HTTP handler
 	mux := http.NewServeMux()
-	registerRoutes(mux, db)
+	registerRoutes(mux, newSQLiteStore(db))
 
 	t.Run("create goal without model/reasoning returns null", func(t *testing.T) {
 		payload := map[string]any{
@@ -32,6 +33,7 @@ func TestModelReasoningFields(t *testing.T) {
 		}
 		body, _ := json.Marshal(payload)
 		req := httptest.NewRequest("POST", "/goals", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 		mux.ServeHTTP(w, req)
 
@@ -47,7 +49,7 @@ func TestModelReasoningFields(t *testing.T) {
 
 		// Get the goal and verify model and reasoning are null
 		id := int64(resp["id"].(float64))
-		g, err := getGoal(db, id)
+		g, err := getGoal(context.Background(), db, id)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -72,6 +74,7 @@ func TestModelReasoningFields(t *testing.T) {
 		}
 		body, _ := json.Marshal(payload)
 		req := httptest.NewRequest("POST", "/goals", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 		mux.ServeHTTP(w, req)
 
@@ -87,7 +90,7 @@ func TestModelReasoningFields(t *testing.T) {
 
 		// Get the goal and verify model and reasoning
 		id := int64(resp["id"].(float64))
-		g, err := getGoal(db, id)
+		g, err := getGoal(context.Background(), db, id)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -109,6 +112,7 @@ func TestModelReasoningFields(t *testing.T) {
 		}
 		body, _ := json.Marshal(payload)
 		req := httptest.NewRequest("POST", "/goals", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 		mux.ServeHTTP(w, req)
 
@@ -136,6 +140,7 @@ func TestModelReasoningFields(t *testing.T) {
 		}
 		body, _ := json.Marshal(payload)
 		req := httptest.NewRequest("POST", "/goals", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 		mux.ServeHTTP(w, req)
 
@@ -157,7 +162,7 @@ func TestModelReasoningFields(t *testing.T) {
 		// Create a goal with model and reasoning
 		model := "sonnet"
 		reasoning := "med"
-		id, err := createGoal(db, "test-org", "test-repo", "Test", "Body", &model, &reasoning)
+		id, err := createGoal(context.Background(), db, "test-org", "test-repo", "Test", "Body", &model, &reasoning)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -185,7 +190,7 @@ func TestModelReasoningFields(t *testing.T) {
 		// Create a goal with model and reasoning
 		model := "haiku"
 		reasoning := "low"
-		_, err := createGoal(db, "test-org", "test-repo", "List Test", "Body", &model, &reasoning)
+		_, err := createGoal(context.Background(), db, "test-org", "test-repo", "List Test", "Body", &model, &reasoning)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -235,6 +240,7 @@ func TestModelReasoningFields(t *testing.T) {
 			}
 			body, _ := json.Marshal(payload)
 			req := httptest.NewRequest("POST", "/goals", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
 			w := httptest.NewRecorder()
 			mux.ServeHTTP(w, req)
 
@@ -257,6 +263,7 @@ func TestModelReasoningFields(t *testing.T) {
 			}
 			body, _ := json.Marshal(payload)
 			req := httptest.NewRequest("POST", "/goals", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
 			w := httptest.NewRecorder()
 			mux.ServeHTTP(w, req)
 
@@ -266,3 +273,89 @@ func TestModelReasoningFields(t *testing.T) {
 		}
 	})
 }
+
+func TestFilterByModelReasoning(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	str := func(s string) *string { return &s }
+
+	opusHigh, err := createGoal(context.Background(), db, "org", "repo", "Opus High", "Body", str("opus"), str("high"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opusLow, err := createGoal(context.Background(), db, "org", "repo", "Opus Low", "Body", str("opus"), str("low"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sonnetHigh, err := createGoal(context.Background(), db, "org", "repo", "Sonnet High", "Body", str("sonnet"), str("high"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unset, err := createGoal(context.Background(), db, "org", "repo", "Unset", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	listIDs := func(url string) []int64 {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		var ids []int64
+		for _, item := range resp["items"].([]any) {
+			ids = append(ids, int64(item.(map[string]any)["id"].(float64)))
+		}
+		return ids
+	}
+
+	assertIDs := func(got []int64, want ...int64) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("expected %v, got %v", want, got)
+			}
+		}
+	}
+
+	t.Run("filter by model", func(t *testing.T) {
+		assertIDs(listIDs("/goals?model=opus"), opusLow, opusHigh)
+	})
+
+	t.Run("filter by reasoning", func(t *testing.T) {
+		assertIDs(listIDs("/goals?reasoning=high"), sonnetHigh, opusHigh)
+	})
+
+	t.Run("filter by model and reasoning", func(t *testing.T) {
+		assertIDs(listIDs("/goals?model=opus&reasoning=high"), opusHigh)
+	})
+
+	t.Run("unset matches null", func(t *testing.T) {
+		assertIDs(listIDs("/goals?model=unset"), unset)
+		assertIDs(listIDs("/goals?reasoning=unset"), unset)
+	})
+
+	t.Run("invalid model rejected", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/goals?model=gpt", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package main
+
+import "sync"
+
+// changeNotifier wakes every goroutine waiting for new goal transitions. It
+// carries no data: waiters re-read the change feed, so a spurious wake-up is
+// harmless. Notify only after the change commits, or the re-read can miss it.
+type changeNotifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newChangeNotifier() *changeNotifier {
+	return &changeNotifier{ch: make(chan struct{})}
+}
+
+// wait returns a channel that is closed by the next notify. Take it before
+// reading the feed, so a transition recorded in between is not missed.
+func (n *changeNotifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ch
+}
+
+func (n *changeNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	close(n.ch)
+	n.ch = make(chan struct{})
+}
+
+// transitionsChanged is notified whenever a goal transition is committed;
+// see inTxNotify.
+var transitionsChanged = newChangeNotifier()
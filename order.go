@@ -0,0 +1,59 @@
+package main
+
+// topoOrder orders goals so that each comes after every goal it depends on,
+// where deps maps a goal to the goals it depends on. Edges to goals outside
+// the set are ignored. Goals are visited in their given order, so with no
+// dependencies between them that order is kept. If the graph has a cycle,
+// order is nil and cycle lists its goal ids, starting and ending with the
+// same id.
+func topoOrder(goals []GoalSummary, deps map[int64][]int64) (order []GoalSummary, cycle []int64) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	byID := make(map[int64]GoalSummary, len(goals))
+	for _, g := range goals {
+		byID[g.ID] = g
+	}
+	state := map[int64]int{}
+	var path []int64
+
+	var visit func(id int64) bool
+	visit = func(id int64) bool {
+		switch state[id] {
+		case visited:
+			return true
+		case visiting:
+			// id is on the current path, so the path from it back to here is the cycle
+			for i, p := range path {
+				if p == id {
+					cycle = append(append([]int64{}, path[i:]...), id)
+					break
+				}
+			}
+			return false
+		}
+		state[id] = visiting
+		path = append(path, id)
+		for _, dep := range deps[id] {
+			if _, ok := byID[dep]; !ok {
+				continue
+			}
+			if !visit(dep) {
+				return false
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+		order = append(order, byID[id])
+		return true
+	}
+
+	for _, g := range goals {
+		if !visit(g.ID) {
+			return nil, cycle
+		}
+	}
+	return order, nil
+}
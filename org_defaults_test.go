@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestOrgDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	do := func(method, path string, payload map[string]any) (*httptest.ResponseRecorder, map[string]any) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w, resp
+	}
+
+	w, resp := do("PUT", "/orgs/team-a/defaults", map[string]any{"model": "opus", "reasoning": "high"})
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %v", w.Code, resp)
+	}
+	if resp["model"] != "opus" || resp["reasoning"] != "high" {
+		t.Fatalf("expected opus/high, got %v", resp)
+	}
+
+	t.Run("goal inherits defaults", func(t *testing.T) {
+		w, resp := do("POST", "/goals", map[string]any{"org": "team-a", "repo": "r", "title": "T", "body": "B"})
+		if w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %v", w.Code, resp)
+		}
+		if resp["model"] != "opus" || resp["reasoning"] != "high" {
+			t.Fatalf("expected inherited opus/high, got %v/%v", resp["model"], resp["reasoning"])
+		}
+	})
+
+	t.Run("explicit model overrides default", func(t *testing.T) {
+		w, resp := do("POST", "/goals", map[string]any{"org": "team-a", "repo": "r", "title": "T", "body": "B", "model": "haiku"})
+		if w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %v", w.Code, resp)
+		}
+		if resp["model"] != "haiku" || resp["reasoning"] != "high" {
+			t.Fatalf("expected haiku/high, got %v/%v", resp["model"], resp["reasoning"])
+		}
+	})
+
+	t.Run("other orgs unaffected", func(t *testing.T) {
+		w, resp := do("POST", "/goals", map[string]any{"org": "team-b", "repo": "r", "title": "T", "body": "B"})
+		if w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %v", w.Code, resp)
+		}
+		if resp["model"] != nil || resp["reasoning"] != nil {
+			t.Fatalf("expected no model/reasoning, got %v/%v", resp["model"], resp["reasoning"])
+		}
+		if w, _ := do("GET", "/orgs/team-b/defaults", nil); w.Code != 404 {
+			t.Fatalf("expected 404 for org without defaults, got %d", w.Code)
+		}
+	})
+
+	t.Run("put replaces defaults", func(t *testing.T) {
+		w, resp := do("PUT", "/orgs/team-a/defaults", map[string]any{"model": "sonnet"})
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %v", w.Code, resp)
+		}
+		if resp["model"] != "sonnet" || resp["reasoning"] != nil {
+			t.Fatalf("expected sonnet with no reasoning, got %v", resp)
+		}
+	})
+
+	t.Run("invalid values rejected", func(t *testing.T) {
+		if w, _ := do("PUT", "/orgs/team-a/defaults", map[string]any{"model": "gpt"}); w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+		if w, _ := do("PUT", "/orgs/team-a/defaults", map[string]any{"reasoning": "max"}); w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+}
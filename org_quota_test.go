@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOrgQuota(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	maxActivePerOrg = 2
+	adminAPIKey = "secret"
+	defer func() { maxActivePerOrg, adminAPIKey = 0, "" }()
+
+	create := func(org string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{"org": org, "repo": "repo", "title": "T", "body": "B"})
+		req := httptest.NewRequest("POST", "/goals", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	setQuota := func(org, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/admin/orgs/"+org+"/quota", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("cap enforced", func(t *testing.T) {
+		var ids []int64
+		for i := range 2 {
+			w := create("busy")
+			if w.Code != 201 {
+				t.Fatalf("goal %d: expected 201, got %d: %s", i+1, w.Code, w.Body.String())
+			}
+			var resp map[string]any
+			json.NewDecoder(w.Body).Decode(&resp)
+			ids = append(ids, int64(resp["id"].(float64)))
+		}
+		if w := create("busy"); w.Code != 429 {
+			t.Fatalf("expected 429 over the cap, got %d", w.Code)
+		}
+		if w := create("other"); w.Code != 201 {
+			t.Fatalf("expected other orgs unaffected, got %d", w.Code)
+		}
+
+		// Terminal goals free up room
+		if err := updateGoalStatus(context.Background(), db, ids[0], "draft", "cancelled"); err != nil {
+			t.Fatal(err)
+		}
+		if w := create("busy"); w.Code != 201 {
+			t.Fatalf("expected 201 after cancelling one, got %d", w.Code)
+		}
+	})
+
+	t.Run("per-org override", func(t *testing.T) {
+		if w := setQuota("big", `{"max_active": 3}`); w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		for i := range 3 {
+			if w := create("big"); w.Code != 201 {
+				t.Fatalf("goal %d: expected 201, got %d", i+1, w.Code)
+			}
+		}
+		if w := create("big"); w.Code != 429 {
+			t.Fatalf("expected 429 over the override, got %d", w.Code)
+		}
+
+		req := httptest.NewRequest("GET", "/orgs/big/quota", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		if resp["max_active"] != float64(3) || resp["active"] != float64(3) {
+			t.Fatalf("expected 3 of 3, got %v", resp)
+		}
+
+		// Removing the override falls back to the global cap
+		if w := setQuota("big", `{"max_active": null}`); w.Code != 200 {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if w := create("big"); w.Code != 429 {
+			t.Fatalf("expected 429 under the global cap, got %d", w.Code)
+		}
+	})
+
+	t.Run("concurrent creates stay under the cap", func(t *testing.T) {
+		dbMaxOpenConns = 4
+		defer func() { dbMaxOpenConns = 1 }()
+		pooled, err := openDB(filepath.Join(tmpDir, "pooled.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pooled.Close()
+
+		var wg sync.WaitGroup
+		var created atomic.Int64
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := insertGoal(context.Background(), pooled, NewGoal{Org: "race", Repo: "repo", Title: "T", Body: "B"})
+				var quota *quotaError
+				switch {
+				case err == nil:
+					created.Add(1)
+				case !errors.As(err, &quota):
+					t.Errorf("expected a quota error, got %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if n := created.Load(); n != 2 {
+			t.Fatalf("expected exactly 2 goals created, got %d", n)
+		}
+	})
+
+	t.Run("override requires admin", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/admin/orgs/big/quota", bytes.NewReader([]byte(`{"max_active": 100}`)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 401 {
+			t.Fatalf("expected 401, got %d", w.Code)
+		}
+	})
+}
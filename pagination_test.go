@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"strconv"
 	"testing"
 )
 
@@ -20,7 +22,7 @@ func TestPagination(t *testing.T) {
 
 	// Create test goals
 	for i := 1; i <= 15; i++ {
-		_, err := createGoal(db, "org1", "repo1", "Goal "+string(rune('A'+i-1)), "Body", nil, nil)
+		_, err := createGoal(context.Background(), db, "org1", "repo1", "Goal "+string(rune('A'+i-1)), "Body", nil, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -28,15 +30,15 @@ func TestPagination(t *testing.T) {
 
 	// Transition all goals to done for easier filtering
 	for i := 1; i <= 15; i++ {
-		err := updateGoalStatus(db, int64(i), "draft", "queued")
+		err := updateGoalStatus(context.Background(), db, int64(i), "draft", "queued")
 		if err != nil {
 			t.Fatal(err)
 		}
-		err = updateGoalStatus(db, int64(i), "queued", "running")
+		err = updateGoalStatus(context.Background(), db, int64(i), "queued", "running")
 		if err != nil {
 			t.Fatal(err)
 		}
-		err = updateGoalStatus(db, int64(i), "running", "done")
+		err = updateGoalStatus(context.Background(), db, int64(i), "running", "done")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -44,7 +46,7 @@ func TestPagination(t *testing.T) {
 
 	// Create HTTP handler
 	mux := http.NewServeMux()
-	registerRoutes(mux, db)
+	registerRoutes(mux, newSQLiteStore(db))
 
 	t.Run("unpaginated returns all results", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/goals?status=done", nil)
@@ -215,4 +217,121 @@ func TestPagination(t *testing.T) {
 			t.Fatalf("expected 400, got %d", w.Code)
 		}
 	})
+
+	t.Run("page metadata", func(t *testing.T) {
+		for _, tc := range []struct {
+			query      string
+			totalPages float64
+			next, prev bool
+		}{
+			{"status=done&page=1&per_page=5", 3, true, false},
+			{"status=done&page=2&per_page=5", 3, true, true},
+			{"status=done&page=3&per_page=5", 3, false, true},
+			{"status=done&page=2&per_page=10", 2, false, true},
+			{"status=queued&page=1&per_page=5", 0, false, false},
+		} {
+			req := httptest.NewRequest("GET", "/goals?"+tc.query, nil)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code != 200 {
+				t.Fatalf("%s: expected 200, got %d", tc.query, w.Code)
+			}
+
+			var resp map[string]any
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp["total_pages"] != tc.totalPages || resp["has_next"] != tc.next || resp["has_prev"] != tc.prev {
+				t.Fatalf("%s: expected total_pages=%v has_next=%v has_prev=%v, got %v %v %v", tc.query,
+					tc.totalPages, tc.next, tc.prev, resp["total_pages"], resp["has_next"], resp["has_prev"])
+			}
+		}
+	})
+}
+
+func TestCursorPagination(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for i := 1; i <= 7; i++ {
+		if _, err := createGoal(context.Background(), db, "org1", "repo1", "Goal", "Body", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	fetch := func(url string) map[string]any {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	t.Run("walks pages without duplicates or gaps", func(t *testing.T) {
+		var seen []int
+		url := "/goals?cursor=&limit=4"
+		for pages := 0; ; pages++ {
+			if pages > 3 {
+				t.Fatal("too many pages")
+			}
+			resp := fetch(url)
+			for _, item := range resp["items"].([]any) {
+				seen = append(seen, int(item.(map[string]any)["id"].(float64)))
+			}
+			if resp["next_cursor"] == nil {
+				break
+			}
+			url = "/goals?limit=4&cursor=" + strconv.Itoa(int(resp["next_cursor"].(float64)))
+		}
+
+		want := []int{7, 6, 5, 4, 3, 2, 1}
+		if len(seen) != len(want) {
+			t.Fatalf("expected ids %v, got %v", want, seen)
+		}
+		for i := range want {
+			if seen[i] != want[i] {
+				t.Fatalf("expected ids %v, got %v", want, seen)
+			}
+		}
+	})
+
+	t.Run("new goals do not shift later pages", func(t *testing.T) {
+		first := fetch("/goals?cursor=&limit=3")
+		next := int(first["next_cursor"].(float64))
+		if next != 5 {
+			t.Fatalf("expected next_cursor=5, got %d", next)
+		}
+
+		if _, err := createGoal(context.Background(), db, "org1", "repo1", "Late Goal", "Body", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		second := fetch("/goals?limit=3&cursor=" + strconv.Itoa(next))
+		items := second["items"].([]any)
+		if id := int(items[0].(map[string]any)["id"].(float64)); id != 4 {
+			t.Fatalf("expected second page to start at id=4, got %d", id)
+		}
+	})
+
+	t.Run("invalid cursor returns error", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/goals?cursor=abc", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
 }
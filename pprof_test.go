@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestPprof(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	adminAPIKey = "secret"
+	defer func() { adminAPIKey = "" }()
+
+	get := func(handler http.Handler, auth bool) int {
+		req := httptest.NewRequest("GET", "/debug/pprof/goroutine?debug=1", nil)
+		if auth {
+			req.Header.Set("Authorization", "Bearer secret")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("absent by default", func(t *testing.T) {
+		mux := http.NewServeMux()
+		registerRoutes(mux, newSQLiteStore(db))
+		if code := get(withRouteErrors(mux), true); code != 404 {
+			t.Fatalf("expected 404, got %d", code)
+		}
+	})
+
+	t.Run("mounted when enabled", func(t *testing.T) {
+		pprofEnabled = true
+		defer func() { pprofEnabled = false }()
+		mux := http.NewServeMux()
+		registerRoutes(mux, newSQLiteStore(db))
+		handler := withRouteErrors(mux)
+
+		if code := get(handler, true); code != 200 {
+			t.Fatalf("expected 200, got %d", code)
+		}
+		if code := get(handler, false); code != 401 {
+			t.Fatalf("expected 401 without the admin key, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestPriority(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	send := func(method, url string, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	createQueued := func(priority any) int64 {
+		payload := map[string]any{"org": "org", "repo": "repo", "title": "Goal", "body": "Body"}
+		if priority != nil {
+			payload["priority"] = priority
+		}
+		w := send("POST", "/goals", payload)
+		if w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		id := int64(resp["id"].(float64))
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	unset := createQueued(nil)
+	low := createQueued(10)
+	high := createQueued(90)
+	lowToo := createQueued(10)
+
+	t.Run("ready list orders by priority then id, null last", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/goals?status=queued&ready=true", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		var got []int64
+		for _, item := range resp["items"].([]any) {
+			got = append(got, int64(item.(map[string]any)["id"].(float64)))
+		}
+
+		want := []int64{high, low, lowToo, unset}
+		if len(got) != len(want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("expected %v, got %v", want, got)
+			}
+		}
+	})
+
+	t.Run("patch sets and clears priority", func(t *testing.T) {
+		url := "/goals/" + strconv.FormatInt(unset, 10) + "/priority"
+		if w := send("PATCH", url, map[string]any{"priority": 50}); w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		g, err := getGoal(context.Background(), db, unset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Priority == nil || *g.Priority != 50 {
+			t.Fatalf("expected priority 50, got %v", g.Priority)
+		}
+
+		if w := send("PATCH", url, map[string]any{"priority": nil}); w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		g, err = getGoal(context.Background(), db, unset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Priority != nil {
+			t.Fatalf("expected priority cleared, got %v", *g.Priority)
+		}
+	})
+
+	t.Run("out of range priority rejected", func(t *testing.T) {
+		w := send("POST", "/goals", map[string]any{"org": "org", "repo": "repo", "title": "Goal", "body": "Body", "priority": 101})
+		if w.Code != 400 {
+			t.Fatalf("expected 400 on create, got %d", w.Code)
+		}
+		w = send("PATCH", "/goals/"+strconv.FormatInt(low, 10)+"/priority", map[string]any{"priority": -1})
+		if w.Code != 400 {
+			t.Fatalf("expected 400 on patch, got %d", w.Code)
+		}
+	})
+
+	t.Run("missing priority rejected", func(t *testing.T) {
+		url := "/goals/" + strconv.FormatInt(low, 10) + "/priority"
+		if w := send("PATCH", url, map[string]any{}); w.Code != 400 {
+			t.Fatalf("expected 400 for an empty body, got %d", w.Code)
+		}
+		g, err := getGoal(context.Background(), db, low)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Priority == nil {
+			t.Fatal("expected priority to be kept")
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, 200, map[string]any{"ok": true})
+	})
+
+	newLimited := func(trustProxy bool) (http.Handler, *time.Time) {
+		rl := newRateLimiter(1, 2, trustProxy)
+		clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		rl.now = func() time.Time { return clock }
+		return rl.wrap(ok), &clock
+	}
+
+	get := func(h http.Handler, path, remote, fwd string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remote
+		if fwd != "" {
+			req.Header.Set("X-Forwarded-For", fwd)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("exceeding the burst returns 429 with Retry-After", func(t *testing.T) {
+		h, clock := newLimited(false)
+		for i := 0; i < 2; i++ {
+			if w := get(h, "/goals", "10.0.0.1:1234", ""); w.Code != 200 {
+				t.Fatalf("request %d: expected 200, got %d", i, w.Code)
+			}
+		}
+		w := get(h, "/goals", "10.0.0.1:1234", "")
+		if w.Code != 429 {
+			t.Fatalf("expected 429, got %d", w.Code)
+		}
+		if ra := w.Header().Get("Retry-After"); ra != "1" {
+			t.Fatalf("expected Retry-After 1, got %q", ra)
+		}
+
+		// Other clients have their own bucket
+		if w := get(h, "/goals", "10.0.0.2:1234", ""); w.Code != 200 {
+			t.Fatalf("expected 200 for another client, got %d", w.Code)
+		}
+
+		// The bucket refills over time
+		*clock = clock.Add(time.Second)
+		if w := get(h, "/goals", "10.0.0.1:1234", ""); w.Code != 200 {
+			t.Fatalf("expected 200 after refill, got %d", w.Code)
+		}
+	})
+
+	t.Run("healthz and metrics are exempt", func(t *testing.T) {
+		h, _ := newLimited(false)
+		for i := 0; i < 5; i++ {
+			for _, path := range []string{"/healthz", "/metrics"} {
+				if w := get(h, path, "10.0.0.1:1234", ""); w.Code != 200 {
+					t.Fatalf("%s: expected 200, got %d", path, w.Code)
+				}
+			}
+		}
+	})
+
+	t.Run("X-Forwarded-For only honored when trusted", func(t *testing.T) {
+		untrusted, _ := newLimited(false)
+		trusted, _ := newLimited(true)
+		for i := 0; i < 2; i++ {
+			get(untrusted, "/goals", "10.0.0.9:1", "1.1.1.1")
+			get(trusted, "/goals", "10.0.0.9:1", "1.1.1.1")
+		}
+		// Same proxy, different forwarded client
+		if w := get(untrusted, "/goals", "10.0.0.9:1", "2.2.2.2"); w.Code != 429 {
+			t.Fatalf("expected untrusted limiter to key on the proxy, got %d", w.Code)
+		}
+		if w := get(trusted, "/goals", "10.0.0.9:1", "2.2.2.2, 10.0.0.9"); w.Code != 200 {
+			t.Fatalf("expected trusted limiter to key on the client, got %d", w.Code)
+		}
+	})
+}
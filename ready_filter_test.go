@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+
+	sqlite "modernc.org/sqlite"
 )
 
 func TestReadyFilter(t *testing.T) {
@@ -18,31 +25,31 @@ func TestReadyFilter(t *testing.T) {
 	defer db.Close()
 
 	// Create goal A (no dependencies)
-	idA, err := createGoal(db, "org1", "repo1", "Goal A", "Body A", nil, nil)
+	idA, err := createGoal(context.Background(), db, "org1", "repo1", "Goal A", "Body A", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Create goal B (depends on A)
-	idB, err := createGoal(db, "org1", "repo1", "Goal B", "Body B", nil, nil)
+	idB, err := createGoal(context.Background(), db, "org1", "repo1", "Goal B", "Body B", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Queue both goals
 	for _, id := range []int64{idA, idB} {
-		if err := updateGoalStatus(db, id, "draft", "queued"); err != nil {
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
 			t.Fatal(err)
 		}
 	}
 
 	// Add dependency: B depends on A
-	if err := addDependency(db, idB, idA); err != nil {
+	if err := addDependency(context.Background(), db, idB, idA); err != nil {
 		t.Fatal(err)
 	}
 
 	mux := http.NewServeMux()
-	registerRoutes(mux, db)
+	registerRoutes(mux, newSQLiteStore(db))
 
 	goalIDs := func(items []any) []int {
 		var ids []int
@@ -101,10 +108,10 @@ func TestReadyFilter(t *testing.T) {
 
 	t.Run("after marking A done, B appears in ready results", func(t *testing.T) {
 		// Transition A to done: queued -> running -> done
-		if err := updateGoalStatus(db, idA, "queued", "running"); err != nil {
+		if err := updateGoalStatus(context.Background(), db, idA, "queued", "running"); err != nil {
 			t.Fatal(err)
 		}
-		if err := updateGoalStatus(db, idA, "running", "done"); err != nil {
+		if err := updateGoalStatus(context.Background(), db, idA, "running", "done"); err != nil {
 			t.Fatal(err)
 		}
 
@@ -124,4 +131,197 @@ func TestReadyFilter(t *testing.T) {
 			t.Fatalf("expected goal B (id=%d) to appear after A is done, got ids=%v", idB, ids)
 		}
 	})
+
+	t.Run("ready without status means queued", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/goals?ready=true", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		ids := goalIDs(resp["items"].([]any))
+
+		// A is done, so only B is listed
+		if len(ids) != 1 || !contains(ids, idB) {
+			t.Fatalf("expected only goal B (id=%d), got ids=%v", idB, ids)
+		}
+	})
+}
+
+// countingConn counts the queries and statements run on a connection.
+type countingConn struct {
+	driver.Conn
+	n *atomic.Int64
+}
+
+func (c countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.n.Add(1)
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.n.Add(1)
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c countingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+type countingConnector struct {
+	dsn string
+	n   *atomic.Int64
+}
+
+func (c countingConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := (&sqlite.Driver{}).Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{conn, c.n}, nil
+}
+
+func (c countingConnector) Driver() driver.Driver { return &sqlite.Driver{} }
+
+func TestReadyFilterLargeQueue(t *testing.T) {
+	// readyQueries fills a database with n queued goals, every other one
+	// blocked on a draft goal, and returns how many queries listing the
+	// ready goals takes
+	readyQueries := func(n int) int64 {
+		var count atomic.Int64
+		dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+		db := sql.OpenDB(countingConnector{dsn, &count})
+		t.Cleanup(func() { db.Close() })
+		db.SetMaxOpenConns(1)
+		if err := migrate(db); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx := context.Background()
+		blocker, err := createGoal(ctx, db, "org1", "repo1", "Blocker", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocked := map[int64]bool{}
+		for i := 0; i < n; i++ {
+			id, err := createGoal(ctx, db, "org1", "repo1", "Goal", "Body", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := updateGoalStatus(ctx, db, id, "draft", "queued"); err != nil {
+				t.Fatal(err)
+			}
+			if i%2 == 0 {
+				if err := addDependency(ctx, db, id, blocker); err != nil {
+					t.Fatal(err)
+				}
+				blocked[id] = true
+			}
+		}
+
+		mux := http.NewServeMux()
+		registerRoutes(mux, newSQLiteStore(db))
+		count.Store(0)
+		req := httptest.NewRequest("GET", "/goals?ready=true", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		queries := count.Load()
+
+		var resp struct {
+			Items []GoalSummary `json:"items"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		if len(resp.Items) != n/2 {
+			t.Fatalf("expected %d ready goals, got %d", n/2, len(resp.Items))
+		}
+		for _, g := range resp.Items {
+			if blocked[g.ID] {
+				t.Fatalf("blocked goal %d returned as ready", g.ID)
+			}
+		}
+		return queries
+	}
+
+	small, large := readyQueries(10), readyQueries(500)
+	if small == 0 || large != small {
+		t.Fatalf("expected the same number of queries for 10 and 500 goals, got %d and %d", small, large)
+	}
+}
+
+func TestBlockedFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	blocker, err := createGoal(context.Background(), db, "org1", "repo1", "Blocker", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready, err := createGoal(context.Background(), db, "org1", "repo1", "Ready", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocked, err := createGoal(context.Background(), db, "org1", "repo1", "Blocked", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addDependency(context.Background(), db, blocked, blocker); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int64{ready, blocked} {
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	t.Run("returns only blocked queued goals", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/goals?status=queued&blocked=true", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		items := resp["items"].([]any)
+		if len(items) != 1 {
+			t.Fatalf("expected 1 blocked goal, got %d", len(items))
+		}
+		if int64(items[0].(map[string]any)["id"].(float64)) != blocked {
+			t.Fatalf("expected goal %d, got %v", blocked, items[0])
+		}
+	})
+
+	t.Run("ready and blocked together rejected", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/goals?ready=true&blocked=true", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("ready with another status rejected", func(t *testing.T) {
+		for _, status := range []string{"done", "queued,running"} {
+			req := httptest.NewRequest("GET", "/goals?ready=true&status="+status, nil)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code != 400 {
+				t.Fatalf("status=%s: expected 400, got %d", status, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), "queued") {
+				t.Fatalf("expected the error to mention queued, got %s", w.Body.String())
+			}
+		}
+	})
 }
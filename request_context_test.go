@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCancelledRequestContext(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	id, err := createGoal(context.Background(), db, "org", "repo", "Goal", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	t.Run("cancelled client gets an error, not data", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest("GET", "/goals/"+strconv.FormatInt(id, 10), nil).WithContext(ctx)
+		w := httptest.NewRecorder()
+
+		start := time.Now()
+		mux.ServeHTTP(w, req)
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("handler took %v after cancellation", elapsed)
+		}
+		if w.Code != 500 {
+			t.Fatalf("expected 500, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("request timeout cuts off a slow query", func(t *testing.T) {
+		// A recursive CTE that never finishes on its own stands in for a stuck query
+		slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var n int
+			err := db.QueryRowContext(r.Context(),
+				`WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT COUNT(*) FROM c`,
+			).Scan(&n)
+			if err == nil {
+				writeJSON(w, 200, map[string]any{"ok": true})
+				return
+			}
+			writeErr(w, 500, "query aborted")
+		})
+
+		req := httptest.NewRequest("GET", "/slow", nil)
+		w := httptest.NewRecorder()
+		start := time.Now()
+		withRequestTimeout(slow, 50*time.Millisecond).ServeHTTP(w, req)
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("handler took %v with a 50ms timeout", elapsed)
+		}
+		if w.Code != 500 {
+			t.Fatalf("expected 500, got %d", w.Code)
+		}
+
+		// The connection is released, so later requests still work
+		if _, err := getGoal(context.Background(), db, id); err != nil {
+			t.Fatalf("db unusable after timeout: %v", err)
+		}
+	})
+
+	t.Run("streaming routes are not timed out", func(t *testing.T) {
+		adminAPIKey = "secret"
+		defer func() { adminAPIKey = "" }()
+
+		h := withRequestTimeout(mux, time.Nanosecond)
+		for _, path := range []string{"/admin/export", "/goals.csv"} {
+			req := httptest.NewRequest("GET", path, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != 200 {
+				t.Fatalf("expected 200 from %s, got %d: %s", path, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), "Goal") {
+				t.Fatalf("expected %s to include the goal, got %q", path, w.Body.String())
+			}
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/goals", nil))
+		if w.Code != 500 {
+			t.Fatalf("expected other routes to still time out, got %d", w.Code)
+		}
+	})
+}
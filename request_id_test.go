@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	tmpDir := t.TempDir()
+	f, err := os.Create(filepath.Join(tmpDir, "access.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lg := &requestLogger{f: f}
+
+	var seen string
+	h := lg.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestID(r.Context())
+		writeJSON(w, 200, map[string]any{"ok": true})
+	}))
+
+	do := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/goals", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		if id != "" {
+			req.Header.Set("X-Request-Id", id)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("generated when absent", func(t *testing.T) {
+		w := do("")
+		id := w.Header().Get("X-Request-Id")
+		if id == "" {
+			t.Fatal("expected an X-Request-Id header")
+		}
+		if seen != id {
+			t.Fatalf("expected handler to see %q, got %q", id, seen)
+		}
+		if other := do("").Header().Get("X-Request-Id"); other == id {
+			t.Fatal("expected distinct IDs per request")
+		}
+	})
+
+	t.Run("incoming ID echoed", func(t *testing.T) {
+		w := do("ui-abc123")
+		if got := w.Header().Get("X-Request-Id"); got != "ui-abc123" {
+			t.Fatalf("expected ui-abc123, got %q", got)
+		}
+	})
+
+	t.Run("oversized ID replaced", func(t *testing.T) {
+		w := do(strings.Repeat("x", maxRequestIDLen+1))
+		if got := w.Header().Get("X-Request-Id"); len(got) > maxRequestIDLen {
+			t.Fatalf("expected a generated ID, got %d bytes", len(got))
+		}
+	})
+
+	t.Run("logged with the entry", func(t *testing.T) {
+		f.Seek(0, io.SeekStart)
+		sc := bufio.NewScanner(f)
+		var ids []string
+		for sc.Scan() {
+			var entry logEntry
+			if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, entry.RequestID)
+		}
+		found := false
+		for _, id := range ids {
+			if id == "ui-abc123" {
+				found = true
+			}
+			if id == "" {
+				t.Fatal("expected every entry to carry a request_id")
+			}
+		}
+		if !found {
+			t.Fatalf("expected ui-abc123 in log, got %v", ids)
+		}
+	})
+}
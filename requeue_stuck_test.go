@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestRequeueStuck(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	newStuck := func(org string) int64 {
+		id, err := createGoal(context.Background(), db, org, "repo", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transitionToRunning(t, db, id)
+		if err := updateGoalStatus(context.Background(), db, id, "running", "stuck"); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	requeueStuck := func(payload any) map[string]any {
+		var body []byte
+		if payload != nil {
+			body, _ = json.Marshal(payload)
+		}
+		req := httptest.NewRequest("POST", "/goals/requeue-stuck", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+
+	t.Run("requeues every stuck goal", func(t *testing.T) {
+		ids := []int64{newStuck("org1"), newStuck("org1"), newStuck("org1")}
+		resp := requeueStuck(map[string]any{"org": "org1"})
+		if resp["count"].(float64) != 3 {
+			t.Fatalf("expected count=3, got %v", resp["count"])
+		}
+		for _, id := range ids {
+			g, err := getGoal(context.Background(), db, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g.Status != "queued" || g.Retries != 1 {
+				t.Fatalf("goal %d: expected queued with retries=1, got %s/%d", id, g.Status, g.Retries)
+			}
+		}
+	})
+
+	t.Run("scoped by org and skips goals at the retry cap", func(t *testing.T) {
+		other := newStuck("org2")
+		capped := newStuck("org3")
+		if _, err := db.Exec(`UPDATE goals SET retries = ? WHERE id = ?`, maxRetries, capped); err != nil {
+			t.Fatal(err)
+		}
+
+		resp := requeueStuck(map[string]any{"org": "org3"})
+		if resp["count"].(float64) != 0 {
+			t.Fatalf("expected count=0, got %v", resp["count"])
+		}
+		skipped := resp["skipped"].([]any)
+		if len(skipped) != 1 || int64(skipped[0].(float64)) != capped {
+			t.Fatalf("expected skipped [%d], got %v", capped, skipped)
+		}
+
+		g, err := getGoal(context.Background(), db, other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Status != "stuck" {
+			t.Fatalf("expected goal outside scope to stay stuck, got %s", g.Status)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestRequeueRetryLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	id, err := createGoal(context.Background(), db, "org", "repo", "Flaky Goal", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
+		t.Fatal(err)
+	}
+
+	requeue := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/goals/"+strconv.FormatInt(id, 10)+"/requeue", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if err := updateGoalStatus(context.Background(), db, id, "queued", "running"); err != nil {
+			t.Fatal(err)
+		}
+		if err := updateGoalStatus(context.Background(), db, id, "running", "stuck"); err != nil {
+			t.Fatal(err)
+		}
+
+		w := requeue()
+		if w.Code != 200 {
+			t.Fatalf("attempt %d: expected 200, got %d: %s", attempt, w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		if int(resp["retries"].(float64)) != attempt {
+			t.Fatalf("attempt %d: expected retries=%d, got %v", attempt, attempt, resp["retries"])
+		}
+	}
+
+	if err := updateGoalStatus(context.Background(), db, id, "queued", "running"); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateGoalStatus(context.Background(), db, id, "running", "stuck"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := requeue()
+	if w.Code != 409 {
+		t.Fatalf("expected 409 once retry limit reached, got %d: %s", w.Code, w.Body.String())
+	}
+
+	g, err := getGoal(context.Background(), db, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Status != "stuck" {
+		t.Fatalf("expected goal to remain stuck, got %s", g.Status)
+	}
+	if g.Retries != maxRetries {
+		t.Fatalf("expected retries=%d, got %d", maxRetries, g.Retries)
+	}
+}
+
+// TestRequeueGoalEnforcesLimit checks the cap at the store layer, where it
+// holds even for a caller that read the goal before another requeue landed.
+func TestRequeueGoalEnforcesLimit(t *testing.T) {
+	db, err := openDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	id, err := createGoal(ctx, db, "org", "repo", "Flaky Goal", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range [][2]string{{"draft", "queued"}, {"queued", "running"}, {"running", "stuck"}} {
+		if err := updateGoalStatus(ctx, db, id, step[0], step[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE goals SET retries = ? WHERE id = ?`, maxRetries, id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := requeueGoal(ctx, db, id, maxRetries, 0, nil); !errors.Is(err, errRetryLimit) {
+		t.Fatalf("expected errRetryLimit, got %v", err)
+	}
+	g, err := getGoal(ctx, db, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Status != "stuck" || g.Retries != maxRetries {
+		t.Fatalf("expected goal left stuck at %d retries, got %s/%d", maxRetries, g.Status, g.Retries)
+	}
+	if _, err := requeueGoal(ctx, db, 999999, maxRetries, 0, nil); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows for a missing goal, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunningTimeoutSweep(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	running := func() int64 {
+		id, err := createGoal(ctx, db, "org", "repo", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, step := range [][2]string{{"draft", "queued"}, {"queued", "running"}} {
+			if err := updateGoalStatus(ctx, db, id, step[0], step[1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return id
+	}
+
+	idle := running()
+	active := running()
+	leased := running()
+	old := timestamp(time.Now().Add(-3 * time.Hour))
+	if _, err := db.Exec(`UPDATE goals SET updated_at = ? WHERE id IN (?, ?)`, old, idle, leased); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`UPDATE goals SET lease_expires_at = ? WHERE id = ?`, timestamp(time.Now().Add(time.Minute)), leased); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := stickIdleRunningGoals(ctx, db, time.Now().Add(-2*time.Hour), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != idle {
+		t.Fatalf("expected only goal %d stuck, got %v", idle, ids)
+	}
+
+	g, err := getGoal(ctx, db, idle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Status != "stuck" || g.StuckReason == nil || *g.StuckReason != runningTimeoutReason {
+		t.Fatalf("expected stuck with reason %q, got %s %v", runningTimeoutReason, g.Status, g.StuckReason)
+	}
+	transitions, err := listTransitions(ctx, db, idle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := transitions[len(transitions)-1]
+	if last.FromStatus == nil || *last.FromStatus != "running" || last.ToStatus != "stuck" || last.Note == nil {
+		t.Fatalf("expected noted running -> stuck transition, got %+v", last)
+	}
+
+	for _, id := range []int64{active, leased} {
+		g, err := getGoal(ctx, db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Status != "running" {
+			t.Fatalf("goal %d: expected to stay running, got %s", id, g.Status)
+		}
+	}
+}
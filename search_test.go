@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestListGoalsSearch(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	limiter, err := createGoal(context.Background(), db, "org1", "repo1", "Add a Rate Limiter", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inBody, err := createGoal(context.Background(), db, "org2", "repo2", "Protect the API", "Put a rate limiter in front of writes", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	percent, err := createGoal(context.Background(), db, "org1", "repo1", "Cap CPU at 50% usage", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createGoal(context.Background(), db, "org1", "repo1", "Cap CPU at 50 usage", "Body", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	search := func(params url.Values) []int64 {
+		req := httptest.NewRequest("GET", "/goals?"+params.Encode(), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		var ids []int64
+		for _, item := range resp["items"].([]any) {
+			ids = append(ids, int64(item.(map[string]any)["id"].(float64)))
+		}
+		return ids
+	}
+
+	t.Run("case-insensitive over title and body", func(t *testing.T) {
+		ids := search(url.Values{"q": {"RATE LIMITER"}})
+		if len(ids) != 2 || ids[0] != inBody || ids[1] != limiter {
+			t.Fatalf("expected [%d %d], got %v", inBody, limiter, ids)
+		}
+	})
+
+	t.Run("combines with org filter", func(t *testing.T) {
+		ids := search(url.Values{"q": {"rate limiter"}, "org": {"org1"}})
+		if len(ids) != 1 || ids[0] != limiter {
+			t.Fatalf("expected [%d], got %v", limiter, ids)
+		}
+	})
+
+	t.Run("literal percent is escaped", func(t *testing.T) {
+		ids := search(url.Values{"q": {"50%"}})
+		if len(ids) != 1 || ids[0] != percent {
+			t.Fatalf("expected [%d], got %v", percent, ids)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestListGoalsSort(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		id, err := createGoal(context.Background(), db, "org1", "repo1", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	// Transition the goals in reverse order, pinning updated_at so the
+	// ordering doesn't depend on second-resolution timestamps.
+	stamps := []string{"2024-01-03T00:00:00Z", "2024-01-02T00:00:00Z", "2024-01-01T00:00:00Z"}
+	for i, id := range ids {
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`UPDATE goals SET updated_at = ? WHERE id = ?`, stamps[i], id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	listIDs := func(url string) []int64 {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		var got []int64
+		for _, item := range resp["items"].([]any) {
+			got = append(got, int64(item.(map[string]any)["id"].(float64)))
+		}
+		return got
+	}
+
+	assertOrder := func(got, want []int64) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("expected %v, got %v", want, got)
+			}
+		}
+	}
+
+	t.Run("default is id descending", func(t *testing.T) {
+		assertOrder(listIDs("/goals"), []int64{ids[2], ids[1], ids[0]})
+	})
+
+	t.Run("updated_at ascending", func(t *testing.T) {
+		assertOrder(listIDs("/goals?sort=updated_at&order=asc"), []int64{ids[2], ids[1], ids[0]})
+	})
+
+	t.Run("id ascending", func(t *testing.T) {
+		assertOrder(listIDs("/goals?sort=id&order=asc"), []int64{ids[0], ids[1], ids[2]})
+	})
+
+	t.Run("unknown sort key rejected", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/goals?sort=title", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("unknown order rejected", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/goals?sort=id&order=sideways", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+}
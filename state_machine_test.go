@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateMachineMatchesSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for from, targets := range validTransitions {
+		for _, to := range targets {
+			t.Run(from+"->"+to, func(t *testing.T) {
+				id, err := createGoal(context.Background(), db, "org", "repo", "Goal", "Body", nil, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := db.Exec(`UPDATE goals SET status = ? WHERE id = ?`, from, id); err != nil {
+					t.Fatalf("schema rejects source status %q: %v", from, err)
+				}
+				if err := updateGoalStatus(context.Background(), db, id, from, to); err != nil {
+					t.Fatalf("schema rejects allowed transition: %v", err)
+				}
+			})
+		}
+	}
+
+	t.Run("every status is referenced by the state machine", func(t *testing.T) {
+		seen := map[string]bool{}
+		for from, targets := range validTransitions {
+			seen[from] = true
+			for _, to := range targets {
+				seen[to] = true
+			}
+		}
+		for _, s := range statuses {
+			if !seen[s] {
+				t.Errorf("status %q is not reachable in validTransitions", s)
+			}
+			delete(seen, s)
+		}
+		for s := range seen {
+			t.Errorf("validTransitions uses %q which is not in statuses", s)
+		}
+	})
+
+	t.Run("schema rejects unknown status", func(t *testing.T) {
+		_, err := db.Exec(`INSERT INTO goals (org, repo, title, body, status) VALUES ('o', 'r', 't', 'b', 'submitted')`)
+		if err == nil {
+			t.Fatal("expected CHECK constraint to reject unknown status")
+		}
+	})
+}
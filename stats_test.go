@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestGoalStats(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	newGoal := func(org string) int64 {
+		id, err := createGoal(context.Background(), db, org, "repo", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	// org1: 2 draft, 1 queued, 1 done; org2: 1 cancelled
+	newGoal("org1")
+	newGoal("org1")
+	queued := newGoal("org1")
+	if err := updateGoalStatus(context.Background(), db, queued, "draft", "queued"); err != nil {
+		t.Fatal(err)
+	}
+	done := newGoal("org1")
+	transitionToRunning(t, db, done)
+	if err := updateGoalStatus(context.Background(), db, done, "running", "done"); err != nil {
+		t.Fatal(err)
+	}
+	cancelled := newGoal("org2")
+	if err := updateGoalStatus(context.Background(), db, cancelled, "draft", "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	fetch := func(url string) map[string]any {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+
+	t.Run("counts every status", func(t *testing.T) {
+		resp := fetch("/goals/stats")
+		counts := resp["counts"].(map[string]any)
+		want := map[string]float64{"draft": 2, "queued": 1, "running": 0, "done": 1, "stuck": 0, "cancelled": 1}
+		if len(counts) != len(want) {
+			t.Fatalf("expected %d statuses, got %v", len(want), counts)
+		}
+		for status, n := range want {
+			if counts[status] != n {
+				t.Fatalf("expected %s=%v, got %v", status, n, counts[status])
+			}
+		}
+		if resp["total"].(float64) != 5 {
+			t.Fatalf("expected total=5, got %v", resp["total"])
+		}
+	})
+
+	t.Run("filtered by org", func(t *testing.T) {
+		resp := fetch("/goals/stats?org=org2")
+		counts := resp["counts"].(map[string]any)
+		if counts["cancelled"].(float64) != 1 || counts["draft"].(float64) != 0 {
+			t.Fatalf("unexpected counts for org2: %v", counts)
+		}
+		if resp["total"].(float64) != 1 {
+			t.Fatalf("expected total=1, got %v", resp["total"])
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestStatusFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	// One goal in each of draft, queued, running and done
+	ctx := context.Background()
+	ids := map[string]int64{}
+	for _, path := range [][]string{nil, {"queued"}, {"queued", "running"}, {"queued", "running", "done"}} {
+		id, err := createGoal(ctx, db, "org", "repo", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from := "draft"
+		for _, to := range path {
+			if err := updateGoalStatus(ctx, db, id, from, to); err != nil {
+				t.Fatal(err)
+			}
+			from = to
+		}
+		ids[from] = id
+	}
+
+	list := func(query string) (int, []int64) {
+		req := httptest.NewRequest("GET", "/goals?"+query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp struct {
+			Items []GoalSummary `json:"items"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		var got []int64
+		for _, g := range resp.Items {
+			got = append(got, g.ID)
+		}
+		slices.Sort(got)
+		return w.Code, got
+	}
+
+	t.Run("several statuses", func(t *testing.T) {
+		code, got := list("status=queued,running")
+		if code != 200 {
+			t.Fatalf("expected 200, got %d", code)
+		}
+		if want := []int64{ids["queued"], ids["running"]}; !slices.Equal(got, want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("single status", func(t *testing.T) {
+		if _, got := list("status=done"); !slices.Equal(got, []int64{ids["done"]}) {
+			t.Fatalf("expected only the done goal, got %v", got)
+		}
+	})
+
+	t.Run("unknown status rejected", func(t *testing.T) {
+		if code, _ := list("status=queued,merged"); code != 400 {
+			t.Fatalf("expected 400, got %d", code)
+		}
+	})
+
+	t.Run("excluded statuses", func(t *testing.T) {
+		other, err := createGoal(ctx, db, "org", "other", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer deleteGoal(ctx, db, other)
+
+		code, got := list("exclude_status=done,cancelled&repo=repo")
+		if code != 200 {
+			t.Fatalf("expected 200, got %d", code)
+		}
+		if want := []int64{ids["draft"], ids["queued"], ids["running"]}; !slices.Equal(got, want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		if code, _ := list("exclude_status=rejected"); code != 400 {
+			t.Fatalf("expected 400 for unknown status, got %d", code)
+		}
+	})
+}
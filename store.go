@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Store is the persistence layer the HTTP handlers depend on. Lookups of a
+// missing row return sql.ErrNoRows regardless of backend.
+type Store interface {
+	Ping(ctx context.Context) error
+	// Backup writes a consistent snapshot of the whole store to w.
+	Backup(ctx context.Context, w io.Writer) error
+
+	// Goals
+	CreateGoal(ctx context.Context, ng NewGoal) (int64, error)
+	GetGoal(ctx context.Context, id int64) (*Goal, error)
+	GoalIDsAfter(ctx context.Context, afterID int64, limit int) ([]int64, error)
+	GoalsAfter(ctx context.Context, f GoalFilter, afterID int64, limit int) ([]Goal, error)
+	ImportGoals(ctx context.Context, goals []GoalExport, preserveIDs bool, mode string) (*ImportResult, error)
+	ListGoals(ctx context.Context, f GoalFilter, limit, offset int) ([]GoalSummary, int, error)
+	ListGoalsAfter(ctx context.Context, f GoalFilter, cursorID int64, limit int) ([]GoalSummary, bool, error)
+	GoalStats(ctx context.Context, org, repo string) (map[string]int, error)
+	CycleTimes(ctx context.Context, org, repo, after, before string) ([]CycleTime, error)
+	UpdateGoal(ctx context.Context, id int64, u GoalUpdate) error
+	UpdateGoalPriority(ctx context.Context, id int64, priority *int) error
+	MergeGoalMetadata(ctx context.Context, id int64, patch map[string]json.RawMessage) (json.RawMessage, error)
+	DeleteGoal(ctx context.Context, id int64) error
+
+	// Status and leases
+	TransitionGoal(ctx context.Context, id int64, from, to string, version int64, note *string) error
+	MarkGoalStuck(ctx context.Context, id int64, from string, version int64, reason, note *string) error
+	CancelGoalCascade(ctx context.Context, id int64, from string, version int64, note *string) ([]int64, error)
+	ClaimNextGoal(ctx context.Context, org, repo string, lease time.Duration) (*Goal, error)
+	ExtendLease(ctx context.Context, id int64, lease time.Duration) (string, error)
+	RequeueExpiredLeases(ctx context.Context, now time.Time, limit int) (requeued, stuck []int64, err error)
+	CancelStaleDrafts(ctx context.Context, cutoff, now time.Time) ([]int64, error)
+	StickIdleRunningGoals(ctx context.Context, cutoff, now time.Time) ([]int64, error)
+	RequeueStuckGoals(ctx context.Context, org, repo string, limit int) (requeued, skipped []int64, err error)
+	RequeueGoal(ctx context.Context, id int64, limit int, version int64, note *string) (int, error)
+	ListTransitions(ctx context.Context, goalID int64) ([]Transition, error)
+	ListActivity(ctx context.Context, goalID int64, limit, offset int) ([]ActivityItem, int, error)
+	ListChanges(ctx context.Context, since int64, limit int) ([]Change, bool, error)
+
+	// Comments
+	CreateComment(ctx context.Context, goalID int64, body string) (int64, error)
+	ListComments(ctx context.Context, goalID int64) ([]Comment, error)
+	UpdateComment(ctx context.Context, goalID, commentID int64, body string) error
+	DeleteComment(ctx context.Context, goalID, commentID int64) error
+
+	// Dependencies
+	AddDependencies(ctx context.Context, goalID int64, dependsOnIDs []int64) error
+	RemoveDependency(ctx context.Context, goalID, dependsOnID int64) error
+	ListDependencies(ctx context.Context, goalID int64) ([]int64, error)
+	ListLinkedGoals(ctx context.Context, goalID int64, dependents, unmetOnly bool, limit, offset int) ([]LinkedGoal, int, error)
+	HasUnmetDependencies(ctx context.Context, goalID int64) (bool, error)
+	CountUnmetDependencies(ctx context.Context, goalID int64) (int, error)
+	CancelledDependency(ctx context.Context, goalID int64) (int64, error)
+	HasDependents(ctx context.Context, goalID int64) (bool, error)
+	DependencyGraph(ctx context.Context, status string) ([]GoalSummary, map[int64][]int64, error)
+
+	// Hierarchy
+	ListChildren(ctx context.Context, parentID int64) ([]GoalSummary, error)
+
+	// Tags
+	AddTag(ctx context.Context, goalID int64, tag string) error
+	RemoveTag(ctx context.Context, goalID int64, tag string) error
+	ListTags(ctx context.Context, goalID int64) ([]string, error)
+
+	// Attachments
+	CreateAttachment(ctx context.Context, goalID int64, name, body string) (int64, error)
+	GetAttachment(ctx context.Context, id int64) (*Attachment, error)
+	ListAttachments(ctx context.Context, goalID int64) ([]AttachmentSummary, error)
+	ListAttachmentBodies(ctx context.Context, goalID int64) ([]Attachment, error)
+	EditAttachmentBody(ctx context.Context, id int64, newBody string) error
+	DeleteAttachment(ctx context.Context, id int64) error
+
+	// Org defaults
+	GetOrgDefaults(ctx context.Context, org string) (*OrgDefaults, error)
+	SetOrgDefaults(ctx context.Context, org string, model, reasoning *string) error
+	OrgQuota(ctx context.Context, org string) (int, error)
+	SetOrgQuota(ctx context.Context, org string, limit *int) error
+	CountActiveGoals(ctx context.Context, org string) (int, error)
+
+	// Webhook outbox
+	PendingOutbox(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error)
+	MarkOutboxDelivered(ctx context.Context, id int64, now time.Time) error
+	RescheduleOutbox(ctx context.Context, id int64, next time.Time) error
+}
+
+// sqliteStore is the SQLite-backed Store, delegating to the functions in db.go.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+var _ Store = (*sqliteStore)(nil)
+
+func newSQLiteStore(db *sql.DB) *sqliteStore {
+	return &sqliteStore{db: db}
+}
+
+func (s *sqliteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *sqliteStore) Backup(ctx context.Context, w io.Writer) error {
+	return backupDB(ctx, s.db, w)
+}
+
+func (s *sqliteStore) CreateGoal(ctx context.Context, ng NewGoal) (int64, error) {
+	return insertGoal(ctx, s.db, ng)
+}
+
+func (s *sqliteStore) GetGoal(ctx context.Context, id int64) (*Goal, error) {
+	return getGoal(ctx, s.db, id)
+}
+
+func (s *sqliteStore) GoalIDsAfter(ctx context.Context, afterID int64, limit int) ([]int64, error) {
+	return goalIDsAfter(ctx, s.db, afterID, limit)
+}
+
+func (s *sqliteStore) GoalsAfter(ctx context.Context, f GoalFilter, afterID int64, limit int) ([]Goal, error) {
+	return goalsAfter(ctx, s.db, f, afterID, limit)
+}
+
+func (s *sqliteStore) ImportGoals(ctx context.Context, goals []GoalExport, preserveIDs bool, mode string) (*ImportResult, error) {
+	return importGoals(ctx, s.db, goals, preserveIDs, mode)
+}
+
+func (s *sqliteStore) ListGoals(ctx context.Context, f GoalFilter, limit, offset int) ([]GoalSummary, int, error) {
+	return listGoals(ctx, s.db, f, limit, offset)
+}
+
+func (s *sqliteStore) ListGoalsAfter(ctx context.Context, f GoalFilter, cursorID int64, limit int) ([]GoalSummary, bool, error) {
+	return listGoalsAfter(ctx, s.db, f, cursorID, limit)
+}
+
+func (s *sqliteStore) GoalStats(ctx context.Context, org, repo string) (map[string]int, error) {
+	return goalStats(ctx, s.db, org, repo)
+}
+
+func (s *sqliteStore) CycleTimes(ctx context.Context, org, repo, after, before string) ([]CycleTime, error) {
+	return cycleTimes(ctx, s.db, org, repo, after, before)
+}
+
+func (s *sqliteStore) UpdateGoal(ctx context.Context, id int64, u GoalUpdate) error {
+	return updateGoal(ctx, s.db, id, u)
+}
+
+func (s *sqliteStore) UpdateGoalPriority(ctx context.Context, id int64, priority *int) error {
+	return updateGoalPriority(ctx, s.db, id, priority)
+}
+
+func (s *sqliteStore) MergeGoalMetadata(ctx context.Context, id int64, patch map[string]json.RawMessage) (json.RawMessage, error) {
+	return mergeGoalMetadata(ctx, s.db, id, patch)
+}
+
+func (s *sqliteStore) DeleteGoal(ctx context.Context, id int64) error {
+	return deleteGoal(ctx, s.db, id)
+}
+
+func (s *sqliteStore) TransitionGoal(ctx context.Context, id int64, from, to string, version int64, note *string) error {
+	return transitionGoal(ctx, s.db, id, from, to, version, note)
+}
+
+func (s *sqliteStore) MarkGoalStuck(ctx context.Context, id int64, from string, version int64, reason, note *string) error {
+	return markGoalStuck(ctx, s.db, id, from, version, reason, note)
+}
+
+func (s *sqliteStore) CancelGoalCascade(ctx context.Context, id int64, from string, version int64, note *string) ([]int64, error) {
+	return cancelGoalCascade(ctx, s.db, id, from, version, note)
+}
+
+func (s *sqliteStore) ClaimNextGoal(ctx context.Context, org, repo string, lease time.Duration) (*Goal, error) {
+	return claimNextGoal(ctx, s.db, org, repo, lease)
+}
+
+func (s *sqliteStore) ExtendLease(ctx context.Context, id int64, lease time.Duration) (string, error) {
+	return extendLease(ctx, s.db, id, lease)
+}
+
+func (s *sqliteStore) RequeueExpiredLeases(ctx context.Context, now time.Time, limit int) (requeued, stuck []int64, err error) {
+	return requeueExpiredLeases(ctx, s.db, now, limit)
+}
+
+func (s *sqliteStore) CancelStaleDrafts(ctx context.Context, cutoff, now time.Time) ([]int64, error) {
+	return cancelStaleDrafts(ctx, s.db, cutoff, now)
+}
+
+func (s *sqliteStore) StickIdleRunningGoals(ctx context.Context, cutoff, now time.Time) ([]int64, error) {
+	return stickIdleRunningGoals(ctx, s.db, cutoff, now)
+}
+
+func (s *sqliteStore) RequeueStuckGoals(ctx context.Context, org, repo string, limit int) (requeued, skipped []int64, err error) {
+	return requeueStuckGoals(ctx, s.db, org, repo, limit)
+}
+
+func (s *sqliteStore) RequeueGoal(ctx context.Context, id int64, limit int, version int64, note *string) (int, error) {
+	return requeueGoal(ctx, s.db, id, limit, version, note)
+}
+
+func (s *sqliteStore) ListTransitions(ctx context.Context, goalID int64) ([]Transition, error) {
+	return listTransitions(ctx, s.db, goalID)
+}
+
+func (s *sqliteStore) ListActivity(ctx context.Context, goalID int64, limit, offset int) ([]ActivityItem, int, error) {
+	return listActivity(ctx, s.db, goalID, limit, offset)
+}
+
+func (s *sqliteStore) ListChanges(ctx context.Context, since int64, limit int) ([]Change, bool, error) {
+	return listChanges(ctx, s.db, since, limit)
+}
+
+func (s *sqliteStore) CreateComment(ctx context.Context, goalID int64, body string) (int64, error) {
+	return createComment(ctx, s.db, goalID, body)
+}
+
+func (s *sqliteStore) ListComments(ctx context.Context, goalID int64) ([]Comment, error) {
+	return listComments(ctx, s.db, goalID)
+}
+
+func (s *sqliteStore) UpdateComment(ctx context.Context, goalID, commentID int64, body string) error {
+	return updateComment(ctx, s.db, goalID, commentID, body)
+}
+
+func (s *sqliteStore) DeleteComment(ctx context.Context, goalID, commentID int64) error {
+	return deleteComment(ctx, s.db, goalID, commentID)
+}
+
+func (s *sqliteStore) AddDependencies(ctx context.Context, goalID int64, dependsOnIDs []int64) error {
+	return addDependencies(ctx, s.db, goalID, dependsOnIDs)
+}
+
+func (s *sqliteStore) RemoveDependency(ctx context.Context, goalID, dependsOnID int64) error {
+	return removeDependency(ctx, s.db, goalID, dependsOnID)
+}
+
+func (s *sqliteStore) ListDependencies(ctx context.Context, goalID int64) ([]int64, error) {
+	return listDependencies(ctx, s.db, goalID)
+}
+
+func (s *sqliteStore) ListLinkedGoals(ctx context.Context, goalID int64, dependents, unmetOnly bool, limit, offset int) ([]LinkedGoal, int, error) {
+	return listLinkedGoals(ctx, s.db, goalID, dependents, unmetOnly, limit, offset)
+}
+
+func (s *sqliteStore) HasUnmetDependencies(ctx context.Context, goalID int64) (bool, error) {
+	return hasUnmetDependencies(ctx, s.db, goalID)
+}
+
+func (s *sqliteStore) CountUnmetDependencies(ctx context.Context, goalID int64) (int, error) {
+	return countUnmetDependencies(ctx, s.db, goalID)
+}
+
+func (s *sqliteStore) CancelledDependency(ctx context.Context, goalID int64) (int64, error) {
+	return cancelledDependency(ctx, s.db, goalID)
+}
+
+func (s *sqliteStore) HasDependents(ctx context.Context, goalID int64) (bool, error) {
+	return hasDependents(ctx, s.db, goalID)
+}
+
+func (s *sqliteStore) DependencyGraph(ctx context.Context, status string) ([]GoalSummary, map[int64][]int64, error) {
+	return dependencyGraph(ctx, s.db, status)
+}
+
+func (s *sqliteStore) ListChildren(ctx context.Context, parentID int64) ([]GoalSummary, error) {
+	return listChildren(ctx, s.db, parentID)
+}
+
+func (s *sqliteStore) AddTag(ctx context.Context, goalID int64, tag string) error {
+	return addTag(ctx, s.db, goalID, tag)
+}
+
+func (s *sqliteStore) RemoveTag(ctx context.Context, goalID int64, tag string) error {
+	return removeTag(ctx, s.db, goalID, tag)
+}
+
+func (s *sqliteStore) ListTags(ctx context.Context, goalID int64) ([]string, error) {
+	return listTags(ctx, s.db, goalID)
+}
+
+func (s *sqliteStore) CreateAttachment(ctx context.Context, goalID int64, name, body string) (int64, error) {
+	return createAttachment(ctx, s.db, goalID, name, body)
+}
+
+func (s *sqliteStore) GetAttachment(ctx context.Context, id int64) (*Attachment, error) {
+	return getAttachment(ctx, s.db, id)
+}
+
+func (s *sqliteStore) ListAttachments(ctx context.Context, goalID int64) ([]AttachmentSummary, error) {
+	return listAttachments(ctx, s.db, goalID)
+}
+
+func (s *sqliteStore) ListAttachmentBodies(ctx context.Context, goalID int64) ([]Attachment, error) {
+	return listAttachmentBodies(ctx, s.db, goalID)
+}
+
+func (s *sqliteStore) EditAttachmentBody(ctx context.Context, id int64, newBody string) error {
+	return editAttachmentBody(ctx, s.db, id, newBody)
+}
+
+func (s *sqliteStore) DeleteAttachment(ctx context.Context, id int64) error {
+	return deleteAttachment(ctx, s.db, id)
+}
+
+func (s *sqliteStore) GetOrgDefaults(ctx context.Context, org string) (*OrgDefaults, error) {
+	return getOrgDefaults(ctx, s.db, org)
+}
+
+func (s *sqliteStore) SetOrgDefaults(ctx context.Context, org string, model, reasoning *string) error {
+	return setOrgDefaults(ctx, s.db, org, model, reasoning)
+}
+
+func (s *sqliteStore) OrgQuota(ctx context.Context, org string) (int, error) {
+	return orgQuota(ctx, s.db, org)
+}
+
+func (s *sqliteStore) SetOrgQuota(ctx context.Context, org string, limit *int) error {
+	return setOrgQuota(ctx, s.db, org, limit)
+}
+
+func (s *sqliteStore) CountActiveGoals(ctx context.Context, org string) (int, error) {
+	return countActiveGoals(ctx, s.db, org)
+}
+
+func (s *sqliteStore) PendingOutbox(ctx context.Context, now time.Time, limit int) ([]OutboxEntry, error) {
+	return pendingOutbox(ctx, s.db, now, limit)
+}
+
+func (s *sqliteStore) MarkOutboxDelivered(ctx context.Context, id int64, now time.Time) error {
+	return markOutboxDelivered(ctx, s.db, id, now)
+}
+
+func (s *sqliteStore) RescheduleOutbox(ctx context.Context, id int64, next time.Time) error {
+	return rescheduleOutbox(ctx, s.db, id, next)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// testStoreConformance exercises the Store contract the handlers rely on.
+// Every backend should pass it.
+func testStoreConformance(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	t.Run("ping", func(t *testing.T) {
+		if err := store.Ping(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("missing goal is sql.ErrNoRows", func(t *testing.T) {
+		if _, err := store.GetGoal(ctx, 999999); err != sql.ErrNoRows {
+			t.Fatalf("expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("goal lifecycle", func(t *testing.T) {
+		id, err := store.CreateGoal(ctx, NewGoal{Org: "org", Repo: "repo", Title: "Goal", Body: "Body"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, step := range [][2]string{{"draft", "queued"}, {"queued", "running"}, {"running", "done"}} {
+			if err := store.TransitionGoal(ctx, id, step[0], step[1], 0, nil); err != nil {
+				t.Fatalf("%s->%s: %v", step[0], step[1], err)
+			}
+		}
+		if err := store.TransitionGoal(ctx, id, "draft", "queued", 0, nil); err != sql.ErrNoRows {
+			t.Fatalf("expected sql.ErrNoRows for stale from status, got %v", err)
+		}
+
+		g, err := store.GetGoal(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Status != "done" {
+			t.Fatalf("expected done, got %s", g.Status)
+		}
+		transitions, err := store.ListTransitions(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(transitions) != 4 {
+			t.Fatalf("expected 4 transitions, got %d", len(transitions))
+		}
+	})
+
+	t.Run("dependencies gate readiness", func(t *testing.T) {
+		dep, err := store.CreateGoal(ctx, NewGoal{Org: "org", Repo: "repo", Title: "Dep", Body: "Body"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		goal, err := store.CreateGoal(ctx, NewGoal{Org: "org", Repo: "repo", Title: "Goal", Body: "Body"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.AddDependencies(ctx, goal, []int64{dep}); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := store.CountUnmetDependencies(ctx, goal); err != nil || n != 1 {
+			t.Fatalf("expected 1 unmet dependency, got %d (%v)", n, err)
+		}
+		if err := store.TransitionGoal(ctx, goal, "draft", "queued", 0, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.ClaimNextGoal(ctx, "org", "repo", leaseDuration); err != sql.ErrNoRows {
+			t.Fatalf("expected no claimable goal, got %v", err)
+		}
+	})
+}
+
+func TestSQLiteStoreConformance(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := openDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testStoreConformance(t, newSQLiteStore(db))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestStuckReason(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		var rd io.Reader
+		if body != "" {
+			rd = strings.NewReader(body)
+		}
+		req := httptest.NewRequest(method, path, rd)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	running := func() string {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+		if err := updateGoalStatus(context.Background(), db, id, "queued", "running"); err != nil {
+			t.Fatal(err)
+		}
+		return "/goals/" + strconv.FormatInt(id, 10)
+	}
+	reason := func(path string) any {
+		w := do("GET", path, "")
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp["stuck_reason"]
+	}
+
+	t.Run("reason round-trips and is cleared on requeue", func(t *testing.T) {
+		path := running()
+		w := do("PATCH", path+"/stuck", `{"reason":"tests keep timing out"}`)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if got := reason(path); got != "tests keep timing out" {
+			t.Fatalf("expected stuck_reason, got %v", got)
+		}
+
+		w = do("PATCH", path+"/requeue", "")
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if got := reason(path); got != nil {
+			t.Fatalf("expected stuck_reason cleared, got %v", got)
+		}
+	})
+
+	t.Run("reason is optional", func(t *testing.T) {
+		path := running()
+		w := do("PATCH", path+"/stuck", "")
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if got := reason(path); got != nil {
+			t.Fatalf("expected no stuck_reason, got %v", got)
+		}
+	})
+
+	t.Run("bulk requeue clears reason", func(t *testing.T) {
+		path := running()
+		do("PATCH", path+"/stuck", `{"reason":"flaky"}`)
+		w := do("POST", "/goals/requeue-stuck", "")
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if got := reason(path); got != nil {
+			t.Fatalf("expected stuck_reason cleared, got %v", got)
+		}
+	})
+
+	t.Run("reason rejected on other transitions", func(t *testing.T) {
+		path := running()
+		w := do("PATCH", path+"/done", `{"reason":"finished"}`)
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+}
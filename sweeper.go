@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// startLeaseSweeper periodically requeues running goals whose worker stopped
+// heartbeating, or marks them stuck once they reach maxRetries, until ctx is
+// cancelled.
+func startLeaseSweeper(ctx context.Context, store Store, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				requeued, stuck, err := store.RequeueExpiredLeases(ctx, now, maxRetries)
+				if err != nil {
+					log.Printf("lease sweep failed: %v", err)
+					continue
+				}
+				for _, id := range requeued {
+					log.Printf("lease expired for goal %d, requeued", id)
+				}
+				for _, id := range stuck {
+					log.Printf("lease expired for goal %d with no retries left, marked stuck", id)
+				}
+			}
+		}
+	}()
+}
+
+// startDraftSweeper periodically cancels drafts older than ttl, until ctx is
+// cancelled.
+func startDraftSweeper(ctx context.Context, store Store, ttl, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				ids, err := store.CancelStaleDrafts(ctx, now.Add(-ttl), now)
+				if err != nil {
+					log.Printf("draft sweep failed: %v", err)
+					continue
+				}
+				for _, id := range ids {
+					log.Printf("draft goal %d older than %s, cancelled", id, ttl)
+				}
+			}
+		}
+	}()
+}
+
+// startRunningTimeoutSweeper periodically moves running goals that have gone
+// timeout without an update to stuck, until ctx is cancelled.
+func startRunningTimeoutSweeper(ctx context.Context, store Store, timeout, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				ids, err := store.StickIdleRunningGoals(ctx, now.Add(-timeout), now)
+				if err != nil {
+					log.Printf("running timeout sweep failed: %v", err)
+					continue
+				}
+				for _, id := range ids {
+					log.Printf("goal %d running without update for %s, marked stuck", id, timeout)
+				}
+			}
+		}
+	}()
+}
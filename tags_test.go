@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	idA, err := createGoal(context.Background(), db, "org", "repo", "Goal A", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idB, err := createGoal(context.Background(), db, "org", "repo", "Goal B", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagsURL := func(id int64) string {
+		return "/goals/" + strconv.FormatInt(id, 10) + "/tags"
+	}
+
+	send := func(method, url string, payload any) *httptest.ResponseRecorder {
+		var body []byte
+		if payload != nil {
+			body, _ = json.Marshal(payload)
+		}
+		req := httptest.NewRequest(method, url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	items := func(w *httptest.ResponseRecorder) []any {
+		t.Helper()
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp["items"].([]any)
+	}
+
+	t.Run("add normalizes to lowercase", func(t *testing.T) {
+		w := send("POST", tagsURL(idA), map[string]any{"tag": "Infra"})
+		if w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+		if w := send("POST", tagsURL(idA), map[string]any{"tag": "urgent"}); w.Code != 201 {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+
+		tags := items(send("GET", tagsURL(idA), nil))
+		if len(tags) != 2 || tags[0] != "infra" || tags[1] != "urgent" {
+			t.Fatalf("expected [infra urgent], got %v", tags)
+		}
+	})
+
+	t.Run("duplicate tag returns 409", func(t *testing.T) {
+		w := send("POST", tagsURL(idA), map[string]any{"tag": "INFRA"})
+		if w.Code != 409 {
+			t.Fatalf("expected 409, got %d", w.Code)
+		}
+	})
+
+	t.Run("invalid tag rejected", func(t *testing.T) {
+		w := send("POST", tagsURL(idA), map[string]any{"tag": "not a tag!"})
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("filter goals by tag", func(t *testing.T) {
+		if w := send("POST", tagsURL(idB), map[string]any{"tag": "infra"}); w.Code != 201 {
+			t.Fatalf("expected 201, got %d", w.Code)
+		}
+
+		goals := items(send("GET", "/goals?tag=urgent", nil))
+		if len(goals) != 1 || int64(goals[0].(map[string]any)["id"].(float64)) != idA {
+			t.Fatalf("expected only goal A for tag=urgent, got %v", goals)
+		}
+
+		goals = items(send("GET", "/goals?tag=infra", nil))
+		if len(goals) != 2 {
+			t.Fatalf("expected 2 goals for tag=infra, got %d", len(goals))
+		}
+	})
+
+	t.Run("remove tag", func(t *testing.T) {
+		w := send("DELETE", tagsURL(idA)+"/urgent", nil)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		tags := items(send("GET", tagsURL(idA), nil))
+		if len(tags) != 1 || tags[0] != "infra" {
+			t.Fatalf("expected [infra], got %v", tags)
+		}
+
+		w = send("DELETE", tagsURL(idA)+"/urgent", nil)
+		if w.Code != 404 {
+			t.Fatalf("expected 404 removing missing tag, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTimestampConsistency(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	id, err := createGoal(context.Background(), db, "org", "repo", "Goal", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
+		t.Fatal(err)
+	}
+	// Claim rather than start so the goal carries a lease timestamp too
+	if _, err := claimNextGoal(context.Background(), db, "", "", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createComment(context.Background(), db, id, "note"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createAttachment(context.Background(), db, id, "plan.md", "text"); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := getGoal(context.Background(), db, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transitions, err := listTransitions(context.Background(), db, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments, err := listComments(context.Background(), db, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachments, err := listAttachments(context.Background(), db, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stamps := []string{g.CreatedAt, g.UpdatedAt, *g.LeaseExpiresAt, comments[0].CreatedAt, attachments[0].CreatedAt, attachments[0].UpdatedAt}
+	for _, tr := range transitions {
+		stamps = append(stamps, tr.CreatedAt)
+	}
+	for _, ts := range stamps {
+		if _, err := time.Parse(timestampLayout, ts); err != nil {
+			t.Errorf("timestamp %q not in %s layout: %v", ts, timestampLayout, err)
+		}
+	}
+
+	if g.UpdatedAt < g.CreatedAt {
+		t.Fatalf("expected updated_at %s >= created_at %s", g.UpdatedAt, g.CreatedAt)
+	}
+}
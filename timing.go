@@ -0,0 +1,61 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// statusDurations totals the seconds a goal spent in each status from its
+// transitions in order: each status lasts from the transition into it until
+// the next one, and the current status until now. A status entered more than
+// once accumulates. Statuses never entered are absent.
+func statusDurations(transitions []Transition, now time.Time) (map[string]int64, error) {
+	durations := map[string]int64{}
+	for i, t := range transitions {
+		entered, err := time.Parse(timestampLayout, t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		left := now
+		if i+1 < len(transitions) {
+			if left, err = time.Parse(timestampLayout, transitions[i+1].CreatedAt); err != nil {
+				return nil, err
+			}
+		}
+		// Clock adjustments can put a later transition earlier; count them as 0
+		durations[t.ToStatus] += max(0, int64(left.Sub(entered)/time.Second))
+	}
+	return durations, nil
+}
+
+// DurationStats summarises a set of durations in seconds. Percentiles use the
+// nearest-rank method, so they are always one of the observed values.
+type DurationStats struct {
+	Count int     `json:"count"`
+	Avg   float64 `json:"avg_seconds"`
+	P50   int64   `json:"p50_seconds"`
+	P90   int64   `json:"p90_seconds"`
+}
+
+// durationStats summarises seconds, which it sorts in place. All fields are
+// zero when seconds is empty.
+func durationStats(seconds []int64) DurationStats {
+	if len(seconds) == 0 {
+		return DurationStats{}
+	}
+	slices.Sort(seconds)
+	var sum int64
+	for _, s := range seconds {
+		sum += s
+	}
+	// rank is the 1-based nearest rank for percentile p
+	rank := func(p int) int64 {
+		return seconds[max(0, (p*len(seconds)+99)/100-1)]
+	}
+	return DurationStats{
+		Count: len(seconds),
+		Avg:   float64(sum) / float64(len(seconds)),
+		P50:   rank(50),
+		P90:   rank(90),
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStatusDurations(t *testing.T) {
+	from := func(s string) *string { return &s }
+	transitions := []Transition{
+		{ToStatus: "draft", CreatedAt: "2026-01-01T00:00:00Z"},
+		{FromStatus: from("draft"), ToStatus: "queued", CreatedAt: "2026-01-01T00:01:00Z"},
+		{FromStatus: from("queued"), ToStatus: "running", CreatedAt: "2026-01-01T00:06:00Z"},
+		{FromStatus: from("running"), ToStatus: "stuck", CreatedAt: "2026-01-01T01:06:00Z"},
+		{FromStatus: from("stuck"), ToStatus: "queued", CreatedAt: "2026-01-01T01:07:00Z"},
+		{FromStatus: from("queued"), ToStatus: "running", CreatedAt: "2026-01-01T01:08:00Z"},
+	}
+	now := time.Date(2026, 1, 1, 1, 10, 30, 0, time.UTC)
+
+	got, err := statusDurations(transitions, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{"draft": 60, "queued": 360, "running": 3750, "stuck": 60}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for status, secs := range want {
+		if got[status] != secs {
+			t.Fatalf("%s: expected %ds, got %ds (all: %v)", status, secs, got[status], got)
+		}
+	}
+
+	if _, err := statusDurations([]Transition{{ToStatus: "draft", CreatedAt: "yesterday"}}, now); err == nil {
+		t.Fatal("expected an error for an unparseable timestamp")
+	}
+}
+
+func TestGoalTiming(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	ctx := context.Background()
+	id, err := createGoal(ctx, db, "org", "repo", "Goal", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := updateGoalStatus(ctx, db, id, "draft", "queued"); err != nil {
+		t.Fatal(err)
+	}
+	// Backdate the history: 2 minutes in draft, queued since an hour ago
+	hourAgo := time.Now().UTC().Add(-time.Hour)
+	db.Exec(`UPDATE goal_transitions SET created_at = ? WHERE goal_id = ? AND to_status = 'draft'`,
+		hourAgo.Add(-2*time.Minute).Format(timestampLayout), id)
+	db.Exec(`UPDATE goal_transitions SET created_at = ? WHERE goal_id = ? AND to_status = 'queued'`,
+		hourAgo.Format(timestampLayout), id)
+
+	get := func(id int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/goals/"+strconv.FormatInt(id, 10)+"/timing", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(id)
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Seconds map[string]int64 `json:"seconds"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Seconds["draft"] != 120 {
+		t.Fatalf("expected 120s in draft, got %v", resp.Seconds)
+	}
+	if q := resp.Seconds["queued"]; q < 3600 || q > 3660 {
+		t.Fatalf("expected about an hour queued, got %v", resp.Seconds)
+	}
+
+	t.Run("missing goal", func(t *testing.T) {
+		if w := get(999); w.Code != 404 {
+			t.Fatalf("expected 404, got %d", w.Code)
+		}
+	})
+}
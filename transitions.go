@@ -1,5 +1,20 @@
 package main
 
+import "strings"
+
+// statuses lists every goal status in lifecycle order.
+var statuses = []string{"draft", "queued", "running", "done", "stuck", "cancelled"}
+
+// statusCheckList renders statuses as a SQL IN list so the goals CHECK
+// constraint is derived from the same set the state machine uses.
+func statusCheckList() string {
+	quoted := make([]string, len(statuses))
+	for i, s := range statuses {
+		quoted[i] = "'" + s + "'"
+	}
+	return strings.Join(quoted, ",")
+}
+
 var validTransitions = map[string][]string{
 	"draft":   {"queued", "cancelled"},
 	"queued":  {"running", "cancelled"},
@@ -20,6 +35,16 @@ func canTransition(from, to string) bool {
 	return false
 }
 
+// transitionError describes a rejected transition, naming the statuses the
+// goal may move to from its current one.
+func transitionError(from, to string) string {
+	msg := "cannot transition from " + from + " to " + to
+	if targets := validTransitions[from]; len(targets) > 0 {
+		return msg + "; allowed: " + strings.Join(targets, ", ")
+	}
+	return msg + "; " + from + " is terminal"
+}
+
 func isTerminal(status string) bool {
 	return status == "done" || status == "cancelled"
 }
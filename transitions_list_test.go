@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestListTransitions(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	t.Run("full lifecycle returns rows in order", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Lifecycle", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transitionToRunning(t, db, id)
+		if err := updateGoalStatus(context.Background(), db, id, "running", "done"); err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest("GET", "/goals/"+strconv.FormatInt(id, 10)+"/transitions", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		items := resp["items"].([]any)
+		if len(items) != 4 {
+			t.Fatalf("expected 4 transitions, got %d", len(items))
+		}
+
+		want := []struct {
+			from any
+			to   string
+		}{
+			{nil, "draft"},
+			{"draft", "queued"},
+			{"queued", "running"},
+			{"running", "done"},
+		}
+		for i, item := range items {
+			m := item.(map[string]any)
+			if m["from_status"] != want[i].from || m["to_status"] != want[i].to {
+				t.Fatalf("transition %d: expected %v->%s, got %v->%v", i, want[i].from, want[i].to, m["from_status"], m["to_status"])
+			}
+			if m["created_at"] == "" {
+				t.Fatalf("transition %d: expected created_at to be set", i)
+			}
+		}
+	})
+
+	lastTransition := func(id int64) map[string]any {
+		req := httptest.NewRequest("GET", "/goals/"+strconv.FormatInt(id, 10)+"/transitions", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		items := resp["items"].([]any)
+		return items[len(items)-1].(map[string]any)
+	}
+
+	t.Run("cancel note is recorded", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Dropped", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("PATCH", "/goals/"+strconv.FormatInt(id, 10)+"/cancel", strings.NewReader(`{"note":"superseded by #42"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		m := lastTransition(id)
+		if m["to_status"] != "cancelled" || m["note"] != "superseded by #42" {
+			t.Fatalf("expected cancelled with note, got %v", m)
+		}
+	})
+
+	t.Run("note defaults to null", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Plain", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("PATCH", "/goals/"+strconv.FormatInt(id, 10)+"/queue", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if m := lastTransition(id); m["note"] != nil {
+			t.Fatalf("expected null note, got %v", m["note"])
+		}
+	})
+
+	t.Run("missing goal returns 404", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/goals/9999/transitions", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		if w.Code != 404 {
+			t.Fatalf("expected 404, got %d", w.Code)
+		}
+	})
+}
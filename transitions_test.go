@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"net/http"
@@ -20,10 +21,10 @@ func TestStatusTransitions(t *testing.T) {
 	defer db.Close()
 
 	mux := http.NewServeMux()
-	registerRoutes(mux, db)
+	registerRoutes(mux, newSQLiteStore(db))
 
 	t.Run("running to done transition works", func(t *testing.T) {
-		id, err := createGoal(db, "org", "repo", "Test Transition", "Body", nil, nil)
+		id, err := createGoal(context.Background(), db, "org", "repo", "Test Transition", "Body", nil, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -40,7 +41,7 @@ func TestStatusTransitions(t *testing.T) {
 		}
 
 		// Verify status
-		g, err := getGoal(db, id)
+		g, err := getGoal(context.Background(), db, id)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -50,22 +51,22 @@ func TestStatusTransitions(t *testing.T) {
 	})
 
 	t.Run("full lifecycle draft to done", func(t *testing.T) {
-		id, err := createGoal(db, "org", "repo", "Test Full Lifecycle", "Body", nil, nil)
+		id, err := createGoal(context.Background(), db, "org", "repo", "Test Full Lifecycle", "Body", nil, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		if err := updateGoalStatus(db, id, "draft", "queued"); err != nil {
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
 			t.Fatal(err)
 		}
-		if err := updateGoalStatus(db, id, "queued", "running"); err != nil {
+		if err := updateGoalStatus(context.Background(), db, id, "queued", "running"); err != nil {
 			t.Fatal(err)
 		}
-		if err := updateGoalStatus(db, id, "running", "done"); err != nil {
+		if err := updateGoalStatus(context.Background(), db, id, "running", "done"); err != nil {
 			t.Fatal(err)
 		}
 
-		g, err := getGoal(db, id)
+		g, err := getGoal(context.Background(), db, id)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -140,15 +141,15 @@ func TestCancelTerminalGoal(t *testing.T) {
 	defer db.Close()
 
 	mux := http.NewServeMux()
-	registerRoutes(mux, db)
+	registerRoutes(mux, newSQLiteStore(db))
 
 	t.Run("cannot cancel done goal", func(t *testing.T) {
-		id, err := createGoal(db, "org", "repo", "Test Cancel Done", "Body", nil, nil)
+		id, err := createGoal(context.Background(), db, "org", "repo", "Test Cancel Done", "Body", nil, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
 		transitionToRunning(t, db, id)
-		if err := updateGoalStatus(db, id, "running", "done"); err != nil {
+		if err := updateGoalStatus(context.Background(), db, id, "running", "done"); err != nil {
 			t.Fatal(err)
 		}
 
@@ -169,11 +170,11 @@ func TestCancelTerminalGoal(t *testing.T) {
 	})
 
 	t.Run("cannot cancel cancelled goal", func(t *testing.T) {
-		id, err := createGoal(db, "org", "repo", "Test Cancel Cancelled", "Body", nil, nil)
+		id, err := createGoal(context.Background(), db, "org", "repo", "Test Cancel Cancelled", "Body", nil, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
-		if err := updateGoalStatus(db, id, "draft", "cancelled"); err != nil {
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "cancelled"); err != nil {
 			t.Fatal(err)
 		}
 
@@ -191,10 +192,106 @@ func TestCancelTerminalGoal(t *testing.T) {
 // Helper function to transition a goal to running status
 func transitionToRunning(t *testing.T, db *sql.DB, id int64) {
 	t.Helper()
-	if err := updateGoalStatus(db, id, "draft", "queued"); err != nil {
+	if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
 		t.Fatal(err)
 	}
-	if err := updateGoalStatus(db, id, "queued", "running"); err != nil {
+	if err := updateGoalStatus(context.Background(), db, id, "queued", "running"); err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestTransitionConflictListsAllowed(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	patch := func(id int64, action string) map[string]any {
+		req := httptest.NewRequest("PATCH", "/goals/"+strconv.FormatInt(id, 10)+"/"+action, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 409 {
+			t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+
+	t.Run("draft goal cannot be marked done", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Draft", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := patch(id, "done")
+		want := "cannot transition from draft to done; allowed: queued, cancelled"
+		if resp["error"] != want {
+			t.Fatalf("expected %q, got %v", want, resp["error"])
+		}
+	})
+
+	t.Run("terminal goal reports no allowed transitions", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Done", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transitionToRunning(t, db, id)
+		if err := updateGoalStatus(context.Background(), db, id, "running", "done"); err != nil {
+			t.Fatal(err)
+		}
+		resp := patch(id, "cancel")
+		want := "cannot transition from done to cancelled; done is terminal"
+		if resp["error"] != want {
+			t.Fatalf("expected %q, got %v", want, resp["error"])
+		}
+	})
+
+	t.Run("requeue on running goal lists allowed", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Running", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transitionToRunning(t, db, id)
+		resp := patch(id, "requeue")
+		want := "cannot transition from running to queued; allowed: done, stuck, cancelled"
+		if resp["error"] != want {
+			t.Fatalf("expected %q, got %v", want, resp["error"])
+		}
+	})
+
+	t.Run("requeue on draft goal is stuck-only", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Draft", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := patch(id, "requeue")
+		want := "only stuck goals can be requeued; queue a draft goal with /queue"
+		if resp["error"] != want {
+			t.Fatalf("expected %q, got %v", want, resp["error"])
+		}
+	})
+
+	t.Run("queue on stuck goal honors retry limit", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Stuck", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transitionToRunning(t, db, id)
+		if err := updateGoalStatus(context.Background(), db, id, "running", "stuck"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`UPDATE goals SET retries = ? WHERE id = ?`, maxRetries, id); err != nil {
+			t.Fatal(err)
+		}
+		resp := patch(id, "queue")
+		if resp["ok"].(bool) {
+			t.Fatal("expected ok=false at retry limit")
+		}
+	})
+}
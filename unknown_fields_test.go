@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestRejectUnknownFields(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	id, err := createGoal(context.Background(), db, "org", "repo", "Goal", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(url string, payload map[string]any) map[string]any {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+
+	t.Run("misspelled field on create", func(t *testing.T) {
+		resp := post("/goals", map[string]any{
+			"org":   "org",
+			"repo":  "repo",
+			"titel": "Typo",
+			"body":  "Body",
+		})
+		if resp["error"] != `unknown field "titel"` {
+			t.Fatalf("expected error naming titel, got %v", resp["error"])
+		}
+	})
+
+	t.Run("camelCase field on dependency", func(t *testing.T) {
+		resp := post("/goals/"+strconv.FormatInt(id, 10)+"/dependencies", map[string]any{"dependsOnId": 5})
+		if resp["error"] != `unknown field "dependsOnId"` {
+			t.Fatalf("expected error naming dependsOnId, got %v", resp["error"])
+		}
+	})
+
+	t.Run("extra field on comment", func(t *testing.T) {
+		resp := post("/goals/"+strconv.FormatInt(id, 10)+"/comments", map[string]any{"body": "hi", "author": "me"})
+		if resp["error"] != `unknown field "author"` {
+			t.Fatalf("expected error naming author, got %v", resp["error"])
+		}
+	})
+}
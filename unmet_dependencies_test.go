@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestUnmetDependencyCount(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	fetch := func(id int64) map[string]any {
+		req := httptest.NewRequest("GET", "/goals/"+strconv.FormatInt(id, 10), nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+
+	newGoal := func(title string) int64 {
+		id, err := createGoal(context.Background(), db, "org", "repo", title, "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	goal := newGoal("Goal")
+	for _, title := range []string{"Dep A", "Dep B"} {
+		if err := addDependency(context.Background(), db, goal, newGoal(title)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("two unmet dependencies", func(t *testing.T) {
+		resp := fetch(goal)
+		if resp["unmet_dependencies"].(float64) != 2 {
+			t.Fatalf("expected unmet_dependencies=2, got %v", resp["unmet_dependencies"])
+		}
+		if resp["blocked"] != true {
+			t.Fatalf("expected blocked=true, got %v", resp["blocked"])
+		}
+	})
+
+	t.Run("no dependencies", func(t *testing.T) {
+		resp := fetch(newGoal("Free"))
+		if resp["unmet_dependencies"].(float64) != 0 {
+			t.Fatalf("expected unmet_dependencies=0, got %v", resp["unmet_dependencies"])
+		}
+		if resp["blocked"] != false {
+			t.Fatalf("expected blocked=false, got %v", resp["blocked"])
+		}
+	})
+}
+
+func TestListUnmetDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	ctx := context.Background()
+	newGoal := func(title string) int64 {
+		id, err := createGoal(ctx, db, "org", "repo", title, "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	goal, done, pending := newGoal("Goal"), newGoal("Done"), newGoal("Pending")
+	for _, dep := range []int64{done, pending} {
+		if err := addDependency(ctx, db, goal, dep); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, step := range [][2]string{{"draft", "queued"}, {"queued", "running"}, {"running", "done"}} {
+		if err := updateGoalStatus(ctx, db, done, step[0], step[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/goals/" + strconv.FormatInt(goal, 10) + "/dependencies?unmet_only=true")
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Items []LinkedGoal `json:"items"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Items) != 1 || resp.Items[0] != (LinkedGoal{ID: pending, Status: "draft"}) {
+		t.Fatalf("expected only the pending dependency, got %+v", resp.Items)
+	}
+
+	t.Run("not for dependents", func(t *testing.T) {
+		if w := get("/goals/" + strconv.FormatInt(done, 10) + "/dependents?unmet_only=true"); w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestUpdateGoalContent(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	patch := func(id int64, payload map[string]any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest("PATCH", "/goals/"+strconv.FormatInt(id, 10), bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("title only", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Typo Titel", "Original body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := patch(id, map[string]any{"title": "Fixed Title"})
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		if resp["title"] != "Fixed Title" {
+			t.Fatalf("expected response title to be updated, got %v", resp["title"])
+		}
+		if resp["body"] != "Original body" {
+			t.Fatalf("expected body unchanged, got %v", resp["body"])
+		}
+	})
+
+	t.Run("body only", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Title", "Short body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := patch(id, map[string]any{"body": "Expanded body"})
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		g, err := getGoal(context.Background(), db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Title != "Title" {
+			t.Fatalf("expected title unchanged, got %s", g.Title)
+		}
+		if g.Body != "Expanded body" {
+			t.Fatalf("expected body updated, got %s", g.Body)
+		}
+	})
+
+	t.Run("empty field rejected", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Title", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := patch(id, map[string]any{"title": ""})
+		if w.Code != 400 {
+			t.Fatalf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("terminal goal rejected", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Title", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "cancelled"); err != nil {
+			t.Fatal(err)
+		}
+
+		w := patch(id, map[string]any{"title": "New Title"})
+		if w.Code != 409 {
+			t.Fatalf("expected 409, got %d", w.Code)
+		}
+
+		g, err := getGoal(context.Background(), db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Title != "Title" {
+			t.Fatalf("expected title unchanged, got %s", g.Title)
+		}
+	})
+}
+
+func TestUpdateGoalModelReasoning(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	patch := func(id int64, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/goals/"+strconv.FormatInt(id, 10), bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	newGoal := func() int64 {
+		model, reasoning := "sonnet", "low"
+		id, err := createGoal(context.Background(), db, "org", "repo", "Title", "Body", &model, &reasoning)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	t.Run("set", func(t *testing.T) {
+		id := newGoal()
+		if w := patch(id, `{"model": "opus", "reasoning": "high"}`); w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		g, err := getGoal(context.Background(), db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Model == nil || *g.Model != "opus" || g.Reasoning == nil || *g.Reasoning != "high" {
+			t.Fatalf("expected opus/high, got %v/%v", g.Model, g.Reasoning)
+		}
+	})
+
+	t.Run("clear with null", func(t *testing.T) {
+		id := newGoal()
+		w := patch(id, `{"model": null, "reasoning": null}`)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp map[string]any
+		json.NewDecoder(w.Body).Decode(&resp)
+		if resp["model"] != nil || resp["reasoning"] != nil {
+			t.Fatalf("expected null model/reasoning in response, got %v/%v", resp["model"], resp["reasoning"])
+		}
+		g, err := getGoal(context.Background(), db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Model != nil || g.Reasoning != nil {
+			t.Fatalf("expected model/reasoning cleared, got %v/%v", g.Model, g.Reasoning)
+		}
+	})
+
+	t.Run("omitted leaves unchanged", func(t *testing.T) {
+		id := newGoal()
+		if w := patch(id, `{"title": "New Title"}`); w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		g, err := getGoal(context.Background(), db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Model == nil || *g.Model != "sonnet" || g.Reasoning == nil || *g.Reasoning != "low" {
+			t.Fatalf("expected sonnet/low unchanged, got %v/%v", g.Model, g.Reasoning)
+		}
+	})
+
+	t.Run("invalid value rejected", func(t *testing.T) {
+		id := newGoal()
+		for _, body := range []string{`{"model": "gpt"}`, `{"reasoning": "max"}`, `{"model": 3}`} {
+			if w := patch(id, body); w.Code != 400 {
+				t.Fatalf("%s: expected 400, got %d", body, w.Code)
+			}
+		}
+	})
+}
+
+func TestUpdateGoalModelStatusGuard(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, newSQLiteStore(db))
+
+	patch := func(id int64, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/goals/"+strconv.FormatInt(id, 10), bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("allowed in draft and queued", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Title", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w := patch(id, `{"model": "sonnet"}`); w.Code != 200 {
+			t.Fatalf("draft: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+		if w := patch(id, `{"model": "opus"}`); w.Code != 200 {
+			t.Fatalf("queued: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		g, err := getGoal(context.Background(), db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Model == nil || *g.Model != "opus" {
+			t.Fatalf("expected model opus, got %v", g.Model)
+		}
+	})
+
+	t.Run("rejected once running", func(t *testing.T) {
+		model := "sonnet"
+		id, err := createGoal(context.Background(), db, "org", "repo", "Title", "Body", &model, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := updateGoalStatus(context.Background(), db, id, "draft", "queued"); err != nil {
+			t.Fatal(err)
+		}
+		if err := updateGoalStatus(context.Background(), db, id, "queued", "running"); err != nil {
+			t.Fatal(err)
+		}
+		if w := patch(id, `{"model": "opus"}`); w.Code != 409 {
+			t.Fatalf("expected 409, got %d", w.Code)
+		}
+		if w := patch(id, `{"reasoning": null}`); w.Code != 409 {
+			t.Fatalf("expected 409 clearing reasoning, got %d", w.Code)
+		}
+		if w := patch(id, `{"title": "Still editable"}`); w.Code != 200 {
+			t.Fatalf("expected title edit to succeed, got %d", w.Code)
+		}
+		g, err := getGoal(context.Background(), db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Model == nil || *g.Model != "sonnet" {
+			t.Fatalf("expected model unchanged, got %v", g.Model)
+		}
+	})
+
+	t.Run("update loses a race with start", func(t *testing.T) {
+		// The handler read the goal while queued; by the time the UPDATE
+		// runs it has started, so the write must not land
+		id, err := createGoal(context.Background(), db, "org", "repo", "Title", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transitionToRunning(t, db, id)
+		opus := "opus"
+		model := &opus
+		err = updateGoal(context.Background(), db, id, GoalUpdate{Model: &model})
+		var notEditable *notEditableError
+		if !errors.As(err, &notEditable) || notEditable.Status != "running" {
+			t.Fatalf("expected a not-editable error for running, got %v", err)
+		}
+		if g, _ := getGoal(context.Background(), db, id); g.Model != nil {
+			t.Fatalf("expected model unchanged, got %v", *g.Model)
+		}
+		if err := updateGoalStatus(context.Background(), db, id, "running", "done"); err != nil {
+			t.Fatal(err)
+		}
+		title := "Too late"
+		if err := updateGoal(context.Background(), db, id, GoalUpdate{Title: &title}); !errors.As(err, &notEditable) {
+			t.Fatalf("expected a not-editable error for done, got %v", err)
+		}
+		if err := updateGoal(context.Background(), db, 999999, GoalUpdate{Title: &title}); err != sql.ErrNoRows {
+			t.Fatalf("expected sql.ErrNoRows for a missing goal, got %v", err)
+		}
+	})
+}
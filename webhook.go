@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// webhookURL receives a POST for every goal status change; empty disables
+// webhooks. Events are queued in the outbox table alongside the change.
+var webhookURL = ""
+
+// A failed delivery is retried after webhookBackoff, doubling with each
+// further attempt up to webhookMaxBackoff. Deliveries are never abandoned.
+var (
+	webhookBackoff    = time.Second
+	webhookMaxBackoff = time.Hour
+)
+
+// webhookBatchSize bounds how many due deliveries one dispatch pass sends.
+const webhookBatchSize = 100
+
+// statusEvent is the payload POSTed for each goal status change.
+type statusEvent struct {
+	ID   int64   `json:"id"`
+	Org  string  `json:"org"`
+	Repo string  `json:"repo"`
+	From *string `json:"from"`
+	To   string  `json:"to"`
+	TS   string  `json:"ts"`
+}
+
+// webhookSender POSTs outbox payloads, signing each body with HMAC-SHA256
+// when a secret is set.
+type webhookSender struct {
+	secret string
+	client *http.Client
+}
+
+func newWebhookSender(secret string) *webhookSender {
+	return &webhookSender{secret: secret, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// signature returns the X-Ralph-Signature value for body.
+func signature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *webhookSender) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		req.Header.Set("X-Ralph-Signature", signature(s.secret, body))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// retryDelay is how long to wait before the next attempt of a delivery that
+// has failed attempts times.
+func retryDelay(attempts int) time.Duration {
+	d := webhookBackoff
+	for i := 1; i < attempts && d < webhookMaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, webhookMaxBackoff)
+}
+
+// dispatchOutbox attempts every due delivery once, marking successes
+// delivered and rescheduling failures.
+func dispatchOutbox(ctx context.Context, store Store, sender *webhookSender, now time.Time) error {
+	entries, err := store.PendingOutbox(ctx, now, webhookBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := sender.post(ctx, e.URL, []byte(e.Payload)); err != nil {
+			log.Printf("webhook delivery %d failed (attempt %d): %v", e.ID, e.Attempts+1, err)
+			if err := store.RescheduleOutbox(ctx, e.ID, now.Add(retryDelay(e.Attempts+1))); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := store.MarkOutboxDelivered(ctx, e.ID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startWebhookDispatcher delivers outbox entries every interval until ctx
+// is cancelled. Entries left undelivered at shutdown are sent after restart.
+func startWebhookDispatcher(ctx context.Context, store Store, sender *webhookSender, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := dispatchOutbox(ctx, store, sender, now); err != nil {
+					log.Printf("webhook dispatch failed: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWebhookOutbox(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := openDB(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fail := true
+	var gotSig string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(503)
+			return
+		}
+		gotSig = r.Header.Get("X-Ralph-Signature")
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	store := newSQLiteStore(db)
+	mux := http.NewServeMux()
+	registerRoutes(mux, store)
+	sender := newWebhookSender("s3cret")
+
+	queue := func(id int64) {
+		req := httptest.NewRequest("PATCH", "/goals/"+strconv.FormatInt(id, 10)+"/queue", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	outboxRow := func() (attempts int, next string, delivered *string) {
+		err := db.QueryRow(`SELECT attempts, next_attempt_at, delivered_at FROM outbox ORDER BY id DESC LIMIT 1`).
+			Scan(&attempts, &next, &delivered)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	t.Run("nothing queued when disabled", func(t *testing.T) {
+		id, err := createGoal(context.Background(), db, "org", "repo", "Goal", "Body", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		queue(id)
+		var n int
+		db.QueryRow(`SELECT COUNT(*) FROM outbox`).Scan(&n)
+		if n != 0 {
+			t.Fatalf("expected empty outbox, got %d rows", n)
+		}
+	})
+
+	webhookURL = srv.URL
+	defer func() { webhookURL = "" }()
+
+	id, err := createGoal(context.Background(), db, "org", "repo", "Goal", "Body", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue(id)
+	now := time.Now().UTC().Add(time.Second)
+
+	t.Run("failed delivery is rescheduled", func(t *testing.T) {
+		_, before, _ := outboxRow()
+		if err := dispatchOutbox(context.Background(), store, sender, now); err != nil {
+			t.Fatal(err)
+		}
+		attempts, next, delivered := outboxRow()
+		if attempts != 1 || delivered != nil {
+			t.Fatalf("expected 1 undelivered attempt, got %d (delivered %v)", attempts, delivered)
+		}
+		if next <= before || next != timestamp(now.Add(webhookBackoff)) {
+			t.Fatalf("expected next_attempt_at advanced to %s, was %s now %s", timestamp(now.Add(webhookBackoff)), before, next)
+		}
+
+		// Not due yet, so a second pass leaves it alone
+		if err := dispatchOutbox(context.Background(), store, sender, now); err != nil {
+			t.Fatal(err)
+		}
+		if attempts, _, _ := outboxRow(); attempts != 1 {
+			t.Fatalf("expected no attempt before next_attempt_at, got %d", attempts)
+		}
+	})
+
+	t.Run("backoff doubles and caps", func(t *testing.T) {
+		if retryDelay(1) != webhookBackoff || retryDelay(3) != 4*webhookBackoff {
+			t.Fatalf("unexpected delays %v, %v", retryDelay(1), retryDelay(3))
+		}
+		if retryDelay(100) != webhookMaxBackoff {
+			t.Fatalf("expected cap %v, got %v", webhookMaxBackoff, retryDelay(100))
+		}
+	})
+
+	t.Run("retry delivers signed payload", func(t *testing.T) {
+		fail = false
+		if err := dispatchOutbox(context.Background(), store, sender, now.Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		attempts, _, delivered := outboxRow()
+		if attempts != 2 || delivered == nil {
+			t.Fatalf("expected delivered on attempt 2, got %d (delivered %v)", attempts, delivered)
+		}
+		if gotSig != signature("s3cret", gotBody) {
+			t.Fatalf("signature mismatch: got %q", gotSig)
+		}
+		var ev statusEvent
+		if err := json.Unmarshal(gotBody, &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.ID != id || ev.Org != "org" || ev.Repo != "repo" || ev.From == nil || *ev.From != "draft" || ev.To != "queued" || ev.TS == "" {
+			t.Fatalf("unexpected payload %s", gotBody)
+		}
+	})
+}